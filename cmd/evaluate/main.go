@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"stackguard-task/internal/corpus"
+	"stackguard-task/internal/detector"
+)
+
+// evaluate runs the testcases corpora through the scanner and reports
+// precision/recall per secret type. It exits non-zero when recall drops
+// below -min-recall so rule changes can be gated in CI.
+func main() {
+    testcasesDir := flag.String("testcases", "./testcases", "directory containing the labelled corpora")
+    rulesFile := flag.String("rules", os.Getenv("RULES_FILE"), "rule pack to evaluate (default: built-in pack)")
+    jsonOutput := flag.Bool("json", false, "print the report as JSON")
+    fullConfusion := flag.Bool("confusion", false, "print the full confusion table instead of its non-zero cells")
+    minRecall := flag.Float64("min-recall", 0, "fail if overall recall is below this value (0.0 - 1.0)")
+    minRuleRecall := flag.Float64("min-rule-recall", 0, "fail if any secret type's recall is below this value (0.0 - 1.0)")
+    entropyScan := flag.Bool("entropy", false, "also run entropy-only discovery")
+    profilesFile := flag.String("profiles", os.Getenv("SCORING_PROFILES"), "scoring profiles to apply (default: built-in profile)")
+    suppressionFile := flag.String("suppression", os.Getenv("SUPPRESSION_FILE"), "suppression vocabulary to apply (default: built-in vocabulary)")
+    flag.Parse()
+
+    scanner, err := detector.LoadSecretScanner(*rulesFile)
+    if err != nil {
+        log.Fatalf("Failed to load detection rules: %v", err)
+    }
+    scanner.SetEntropyScan(*entropyScan)
+
+    profiles, err := detector.LoadProfiles(*profilesFile)
+    if err != nil {
+        log.Fatalf("Failed to load scoring profiles: %v", err)
+    }
+    scanner.SetProfiles(profiles)
+
+    vocabulary, err := detector.LoadSuppressionVocabulary(*suppressionFile)
+    if err != nil {
+        log.Fatalf("Failed to load suppression vocabulary: %v", err)
+    }
+    scanner.SetSuppressionVocabulary(vocabulary)
+
+    cases, err := corpus.LoadDir(*testcasesDir)
+    if err != nil {
+        log.Fatalf("Failed to load testcases: %v", err)
+    }
+
+    report := corpus.Evaluate(scanner, cases)
+
+    if *jsonOutput {
+        encoder := json.NewEncoder(os.Stdout)
+        encoder.SetIndent("", "  ")
+        if err := encoder.Encode(report); err != nil {
+            log.Fatalf("Failed to encode report: %v", err)
+        }
+    } else {
+        printReport(report, *fullConfusion)
+    }
+
+    var breaches []string
+    if report.Overall.Recall < *minRecall {
+        breaches = append(breaches, fmt.Sprintf("overall recall %.2f < %.2f", report.Overall.Recall, *minRecall))
+    }
+    if *minRuleRecall > 0 {
+        for _, rule := range report.Rules {
+            // Types that never appear in the labels have nothing to recall
+            if rule.TruePositives+rule.FalseNegatives == 0 {
+                continue
+            }
+            if rule.Recall < *minRuleRecall {
+                breaches = append(breaches, fmt.Sprintf("%s recall %.2f < %.2f", rule.SecretType, rule.Recall, *minRuleRecall))
+            }
+        }
+    }
+
+    if len(breaches) > 0 {
+        fmt.Fprintf(os.Stderr, "Recall floor breached: %s\n", strings.Join(breaches, "; "))
+        os.Exit(1)
+    }
+}
+
+func printReport(report corpus.EvaluationReport, fullConfusion bool) {
+    fmt.Printf("Rule pack version: %s\n\n", report.RulesVersion)
+
+    fmt.Println("Cases")
+    w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "RESULT\tSOURCE\tDESCRIPTION\tMISSED\tUNEXPECTED")
+    for _, result := range report.Cases {
+        status := "PASS"
+        if !result.Passed() {
+            status = "FAIL"
+        }
+        fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status, result.Source, result.Description,
+            joinOrDash(result.Missed), joinOrDash(result.Unexpected))
+    }
+    w.Flush()
+
+    fmt.Println("\nPer secret type")
+    w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "SECRET TYPE\tTP\tFP\tFN\tTN\tPRECISION\tRECALL")
+    for _, rule := range append(report.Rules, report.Overall) {
+        fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%.2f\t%.2f\n", rule.SecretType,
+            rule.TruePositives, rule.FalsePositives, rule.FalseNegatives, rule.TrueNegatives,
+            rule.Precision, rule.Recall)
+    }
+    w.Flush()
+
+    if fullConfusion {
+        fmt.Println("\nConfusion (rows: expected, columns: detected)")
+        printConfusion(report.Confusion)
+        return
+    }
+
+    fmt.Println("\nConfusion (non-zero cells, -confusion for the full table)")
+    w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "EXPECTED\tDETECTED\tCASES")
+    for _, cell := range report.ConfusionCells() {
+        fmt.Fprintf(w, "%s\t%s\t%d\n", cell.Expected, cell.Detected, cell.Count)
+    }
+    w.Flush()
+}
+
+func printConfusion(confusion map[string]map[string]int) {
+    labelSet := make(map[string]bool)
+    for expected, row := range confusion {
+        labelSet[expected] = true
+        for detected := range row {
+            labelSet[detected] = true
+        }
+    }
+
+    var labels []string
+    for label := range labelSet {
+        labels = append(labels, label)
+    }
+    sort.Strings(labels)
+
+    w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+    fmt.Fprint(w, "\t")
+    for _, label := range labels {
+        fmt.Fprintf(w, "%s\t", label)
+    }
+    fmt.Fprintln(w)
+    for _, expected := range labels {
+        fmt.Fprintf(w, "%s\t", expected)
+        for _, detected := range labels {
+            fmt.Fprintf(w, "%d\t", confusion[expected][detected])
+        }
+        fmt.Fprintln(w)
+    }
+    w.Flush()
+}
+
+func joinOrDash(values []string) string {
+    if len(values) == 0 {
+        return "-"
+    }
+    return strings.Join(values, ", ")
+}
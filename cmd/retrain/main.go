@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"stackguard-task/internal/detector"
+	"stackguard-task/internal/feedback"
+)
+
+// retrain learns scoring from analyst feedback exported by the server
+// (GET /api/feedback/samples) and reports how scores on those detections
+// shift against the current model. The new model is written to -out, which
+// the server loads at startup through FEEDBACK_MODEL.
+func main() {
+    samplesFile := flag.String("samples", "", "labelled samples: a JSON array or the /api/feedback/samples response")
+    modelFile := flag.String("model", os.Getenv("FEEDBACK_MODEL"), "current model to compare against (default: built-in weights)")
+    outFile := flag.String("out", "", "where to write the retrained model (default: -model, empty -model only prints the report)")
+    jsonOutput := flag.Bool("json", false, "print the report as JSON")
+    flag.Parse()
+
+    if *samplesFile == "" {
+        log.Fatal("-samples is required")
+    }
+    samples, err := loadSamples(*samplesFile)
+    if err != nil {
+        log.Fatalf("Failed to load samples: %v", err)
+    }
+
+    var current *detector.Calibration
+    if *modelFile != "" {
+        if _, err := os.Stat(*modelFile); err == nil {
+            if current, err = detector.LoadCalibration(*modelFile); err != nil {
+                log.Fatalf("Failed to load current model: %v", err)
+            }
+        }
+    }
+
+    calibration, err := feedback.Train(samples)
+    if err != nil {
+        log.Fatalf("Retraining failed: %v", err)
+    }
+    report := feedback.Compare(samples, current, calibration)
+
+    if *jsonOutput {
+        encoder := json.NewEncoder(os.Stdout)
+        encoder.SetIndent("", "  ")
+        if err := encoder.Encode(report); err != nil {
+            log.Fatalf("Failed to encode report: %v", err)
+        }
+    } else {
+        printReport(report)
+    }
+
+    out := *outFile
+    if out == "" {
+        out = *modelFile
+    }
+    if out != "" {
+        if err := detector.SaveCalibration(out, calibration); err != nil {
+            log.Fatalf("Failed to save model: %v", err)
+        }
+        fmt.Fprintf(os.Stderr, "Model written to %s\n", out)
+    }
+}
+
+// loadSamples accepts a bare array of samples or an API response wrapping one
+func loadSamples(path string) ([]feedback.Sample, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, err
+    }
+
+    var samples []feedback.Sample
+    if err := json.Unmarshal(data, &samples); err == nil {
+        return samples, nil
+    }
+    var response struct {
+        Data []feedback.Sample `json:"data"`
+    }
+    if err := json.Unmarshal(data, &response); err != nil {
+        return nil, fmt.Errorf("parsing %s: %w", path, err)
+    }
+    if response.Data == nil {
+        return nil, errors.New(path + " holds no samples")
+    }
+    return response.Data, nil
+}
+
+func printReport(report feedback.Report) {
+    fmt.Printf("Labelled detections: %d (%d real, %d false positives)\n\n", report.Samples, report.Real, report.FalsePositives)
+
+    fmt.Println("Factor weights")
+    w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "FACTOR\tBEFORE\tAFTER")
+    for _, weight := range report.Weights {
+        fmt.Fprintf(w, "%s\t%.3f\t%.3f\n", weight.Factor, weight.Before, weight.After)
+    }
+    w.Flush()
+
+    fmt.Println("\nScores")
+    w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "\tMEAN REAL\tMEAN FALSE POSITIVE\tACCURACY")
+    fmt.Fprintf(w, "before\t%.3f\t%.3f\t%.3f\n", report.Before.MeanReal, report.Before.MeanFalsePositive, report.Before.Accuracy)
+    fmt.Fprintf(w, "after\t%.3f\t%.3f\t%.3f\n", report.After.MeanReal, report.After.MeanFalsePositive, report.After.Accuracy)
+    w.Flush()
+
+    fmt.Println("\nPer rule")
+    w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+    fmt.Fprintln(w, "RULE\tREAL\tFP\tSHIFT\tBEFORE\tAFTER")
+    for _, rule := range report.Rules {
+        fmt.Fprintf(w, "%s\t%d\t%d\t%+.3f\t%.3f\t%.3f\n", rule.RuleID, rule.Real, rule.FalsePositives, rule.Shift, rule.Before, rule.After)
+    }
+    w.Flush()
+}
@@ -4,7 +4,9 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -14,6 +16,7 @@ import (
 	"stackguard-task/internal/api"
 	"stackguard-task/internal/config"
 	"stackguard-task/internal/constants"
+	"stackguard-task/internal/detector"
 	"stackguard-task/internal/services"
 	"stackguard-task/internal/storage"
 	"stackguard-task/internal/websocket"
@@ -29,8 +32,54 @@ func main() {
     wsHub := websocket.NewHub()
     go wsHub.Run()
 
+    // Load detection rules, refusing to start on an invalid rule pack
+    scanner, err := detector.LoadSecretScanner(cfg.RulesFile)
+    if err != nil {
+        log.Fatalf("Failed to load detection rules: %v", err)
+    }
+    log.Printf("Loaded detection rule pack version %s", scanner.RulesVersion())
+    scanner.SetEntropyScan(cfg.EntropyScan)
+
+    // Stricter or more lenient scoring for particular rules, channels and teams
+    profiles, err := detector.LoadProfiles(cfg.ScoringProfiles)
+    if err != nil {
+        log.Fatalf("Failed to load scoring profiles: %v", err)
+    }
+    scanner.SetProfiles(profiles)
+
+    // Context keywords in the languages teams chat in
+    dictionaries, err := detector.LoadKeywordDictionaries(cfg.KeywordsDir)
+    if err != nil {
+        log.Fatalf("Failed to load keyword dictionaries: %v", err)
+    }
+    scanner.SetKeywordDictionaries(dictionaries)
+    log.Printf("Context keywords for English plus %s", strings.Join(dictionaries.Languages(), ", "))
+
+    // Words and phrases that mark test and placeholder values
+    vocabulary, err := detector.LoadSuppressionVocabulary(cfg.SuppressionFile)
+    if err != nil {
+        log.Fatalf("Failed to load suppression vocabulary: %v", err)
+    }
+    scanner.SetSuppressionVocabulary(vocabulary)
+
+    // Scoring learned from analyst feedback, if it has been trained before
+    if cfg.FeedbackModel != "" {
+        if _, err := os.Stat(cfg.FeedbackModel); err == nil {
+            calibration, err := detector.LoadCalibration(cfg.FeedbackModel)
+            if err != nil {
+                log.Fatalf("Failed to load feedback model: %v", err)
+            }
+            scanner.SetCalibration(calibration)
+            log.Printf("Loaded feedback model trained on %d labelled detections", calibration.Samples)
+        }
+    }
+
+    // Pick up rule file edits without a restart
+    stopRuleWatch := make(chan struct{})
+    go scanner.WatchRuleFile(time.Duration(cfg.RulesReloadInterval)*time.Second, stopRuleWatch)
+
     alertService := services.NewAlertService(cfg, wsHub)
-    teamsService := services.NewTeamsService(cfg, store, alertService)
+    teamsService := services.NewTeamsService(cfg, scanner, store, alertService)
     
     app := fiber.New(fiber.Config{
         AppName: "Teams Security Connector",
@@ -80,6 +129,7 @@ func main() {
     <-quit
     
     log.Println("Shutting down server...")
+    close(stopRuleWatch)
     if err := app.Shutdown(); err != nil {
         log.Fatalf("Server forced to shutdown: %v", err)
     }
@@ -100,8 +150,25 @@ func setupRoutes(app *fiber.App, handler *api.Handler, wsHub *websocket.Hub) {
     apiGroup.Get(constants.DetectionsByChannelRoute, handler.GetDetectionsByChannel)
     apiGroup.Get(constants.DetectionsByStatusRoute, handler.GetDetectionsByStatus)
     apiGroup.Put(constants.DetectionStatusRoute, handler.UpdateDetectionStatus)
+    apiGroup.Get(constants.DetectionExplainRoute, handler.ExplainDetection)
     apiGroup.Delete(constants.ClearDetectionsRoute, handler.ClearDetections)
     
+    // Rule management
+    apiGroup.Get(constants.RulesRoute, handler.GetRules)
+    apiGroup.Post(constants.RulesRoute, handler.CreateRule)
+    apiGroup.Post(constants.RulesTestRoute, handler.TestRule)
+    apiGroup.Post(constants.RulesReloadRoute, handler.ReloadRules)
+    apiGroup.Put(constants.RuleStatusRoute, handler.UpdateRuleStatus)
+    apiGroup.Delete(constants.RuleRoute, handler.DeleteRule)
+    
+    // Analyst feedback
+    apiGroup.Get(constants.FeedbackSamplesRoute, handler.GetFeedbackSamples)
+    apiGroup.Get(constants.FeedbackModelRoute, handler.GetFeedbackModel)
+    apiGroup.Post(constants.FeedbackRetrainRoute, handler.RetrainFeedback)
+    
+    // Suppression audit trail
+    apiGroup.Get(constants.SuppressionsRoute, handler.GetSuppressions)
+    
     // Webhook endpoints
     apiGroup.Post(constants.TeamsWebhookRoute, handler.TeamsWebhook)
     apiGroup.Post(constants.TestDetectionRoute, handler.TestSecretDetection)
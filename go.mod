@@ -3,14 +3,16 @@ module stackguard-task
 go 1.24.6
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/gofiber/contrib/websocket v1.3.4
 	github.com/joho/godotenv v1.5.1
+	golang.org/x/net v0.38.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/fasthttp/websocket v1.5.8 // indirect
 	github.com/savsgio/gotils v0.0.0-20240303185622-093b76447511 // indirect
-	golang.org/x/net v0.38.0 // indirect
 )
 
 require (
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"stackguard-task/internal/constants"
+	"stackguard-task/internal/feedback"
+	"stackguard-task/internal/models"
+)
+
+// GetFeedbackSamples lists the analyst-labelled detections retraining learns from
+func (h *Handler) GetFeedbackSamples(c *fiber.Ctx) error {
+    samples, err := h.teamsService.FeedbackSamples()
+    if err != nil {
+        return c.Status(500).JSON(models.APIResponse{
+            Success: false,
+            Error:   err.Error(),
+        })
+    }
+
+    return c.JSON(models.APIResponse{
+        Success: true,
+        Data:    samples,
+    })
+}
+
+// GetFeedbackModel shows the calibration scoring currently uses
+func (h *Handler) GetFeedbackModel(c *fiber.Ctx) error {
+    model := h.teamsService.FeedbackModel()
+    if model == nil {
+        return c.JSON(models.APIResponse{
+            Success: true,
+            Message: constants.MsgNoFeedbackModel,
+        })
+    }
+
+    return c.JSON(models.APIResponse{
+        Success: true,
+        Data:    model,
+    })
+}
+
+// RetrainFeedback retrains scoring from analyst feedback and reports how
+// scores on the labelled detections shift
+func (h *Handler) RetrainFeedback(c *fiber.Ctx) error {
+    report, err := h.teamsService.Retrain()
+    if errors.Is(err, feedback.ErrNotEnoughFeedback) {
+        return c.Status(422).JSON(models.APIResponse{
+            Success: false,
+            Error:   err.Error(),
+        })
+    }
+    if err != nil && report.Samples == 0 {
+        return c.Status(500).JSON(models.APIResponse{
+            Success: false,
+            Error:   err.Error(),
+        })
+    }
+    if err != nil {
+        return c.Status(500).JSON(models.APIResponse{
+            Success: false,
+            Data:    report,
+            Error:   err.Error(),
+            Message: constants.ErrModelSaveFailed,
+        })
+    }
+
+    return c.JSON(models.APIResponse{
+        Success: true,
+        Data:    report,
+        Message: constants.MsgFeedbackRetrained,
+    })
+}
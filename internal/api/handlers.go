@@ -25,16 +25,41 @@ func NewHandler(teamsService *services.TeamsService, alertService *services.Aler
 }
 
 func (h *Handler) HealthCheck(c *fiber.Ctx) error {
+    rulesStatus := h.teamsService.GetRulesStatus()
+    
+    // A failed rule reload leaves the service running on the previous rules
+    status := "healthy"
+    if !rulesStatus.Healthy() {
+        status = "degraded"
+    }
+    
     return c.JSON(models.APIResponse{
         Success: true,
         Data: fiber.Map{
-            "status":    "healthy",
+            "status":    status,
             "service":   "teams-connector",
             "timestamp": time.Now(),
+            "rules":     rulesStatus,
         },
     })
 }
 
+func (h *Handler) ReloadRules(c *fiber.Ctx) error {
+    if err := h.teamsService.ReloadRules(); err != nil {
+        return c.Status(422).JSON(models.APIResponse{
+            Success: false,
+            Error:   err.Error(),
+            Message: constants.ErrRulesReloadFailed,
+        })
+    }
+    
+    return c.JSON(models.APIResponse{
+        Success: true,
+        Data:    h.teamsService.GetRulesStatus(),
+        Message: constants.MsgRulesReloaded,
+    })
+}
+
 func (h *Handler) GetStats(c *fiber.Ctx) error {
     stats, err := h.teamsService.GetStats()
     if err != nil {
@@ -80,7 +105,8 @@ func (h *Handler) GetDetectionsByChannel(c *fiber.Ctx) error {
         })
     }
     
-    detections, err := h.teamsService.GetDetectionsByChannel(channelID)
+    // Optional ?threadId= narrows the result to one conversation thread
+    detections, err := h.teamsService.GetDetectionsByChannel(channelID, c.Query("threadId"))
     if err != nil {
         return c.Status(500).JSON(models.APIResponse{
             Success: false,
@@ -135,6 +161,37 @@ func (h *Handler) UpdateDetectionStatus(c *fiber.Ctx) error {
     })
 }
 
+// ExplainDetection shows the factors and adjustments behind a detection's confidence
+func (h *Handler) ExplainDetection(c *fiber.Ctx) error {
+    id := c.Params("id")
+    if id == "" {
+        return c.Status(400).JSON(models.APIResponse{
+            Success: false,
+            Error:   constants.ErrDetectionIDRequired,
+        })
+    }
+    
+    detection, err := h.teamsService.GetDetection(id)
+    if err != nil {
+        return c.Status(404).JSON(models.APIResponse{
+            Success: false,
+            Error:   constants.ErrDetectionNotFound,
+        })
+    }
+    
+    return c.JSON(models.APIResponse{
+        Success: true,
+        Data: fiber.Map{
+            "id":          detection.ID,
+            "secretType":  detection.SecretType,
+            "ruleId":      detection.RuleID,
+            "confidence":  detection.Confidence,
+            "profile":     detection.Profile,
+            "explanation": detection.Explanation,
+        },
+    })
+}
+
 func (h *Handler) TeamsWebhook(c *fiber.Ctx) error {
     var payload models.WebhookPayload
     
@@ -154,6 +211,15 @@ func (h *Handler) TeamsWebhook(c *fiber.Ctx) error {
     if payload.Timestamp.IsZero() {
         payload.Timestamp = time.Now()
     }
+    // A reply may carry the message it answers, its text is scoring context
+    if parent := payload.ParentMessage; parent != nil {
+        if payload.Message.ReplyToID == "" {
+            payload.Message.ReplyToID = parent.ID
+        }
+        if payload.Message.ParentBody == nil {
+            payload.Message.ParentBody = &parent.Body
+        }
+    }
     
     detections, err := h.teamsService.ProcessMessage(payload.Message)
     if err != nil {
@@ -226,9 +292,12 @@ func (h *Handler) GetDetectionsByStatus(c *fiber.Ctx) error {
 
 func (h *Handler) TestSecretDetection(c *fiber.Ctx) error {
     var request struct {
-        Text      string `json:"text"`
-        ChannelID string `json:"channelId"`
-        UserName  string `json:"userName"`
+        Text        string                     `json:"text"`
+        ChannelID   string                     `json:"channelId"`
+        UserName    string                     `json:"userName"`
+        ReplyToID   string                     `json:"replyToId"`
+        ParentText  string                     `json:"parentText"` // text of the replied-to message, for thread context
+        Attachments []models.MessageAttachment `json:"attachments"`
     }
     
     if err := c.BodyParser(&request); err != nil {
@@ -238,7 +307,7 @@ func (h *Handler) TestSecretDetection(c *fiber.Ctx) error {
         })
     }
     
-    if request.Text == "" {
+    if request.Text == "" && len(request.Attachments) == 0 {
         return c.Status(400).JSON(models.APIResponse{
             Success: false,
             Error:   "Text is required",
@@ -265,6 +334,11 @@ func (h *Handler) TestSecretDetection(c *fiber.Ctx) error {
                 UserType:    "user",
             },
         },
+        ReplyToID:   request.ReplyToID,
+        Attachments: request.Attachments,
+    }
+    if request.ParentText != "" {
+        mockMessage.ParentBody = &models.MessageBody{ContentType: "text", Content: request.ParentText}
     }
     
     detections, err := h.teamsService.ProcessMessage(mockMessage)
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+
+	"stackguard-task/internal/constants"
+	"stackguard-task/internal/detector"
+	"stackguard-task/internal/models"
+	"stackguard-task/internal/services"
+)
+
+func (h *Handler) GetRules(c *fiber.Ctx) error {
+    return c.JSON(models.APIResponse{
+        Success: true,
+        Data:    h.teamsService.GetRulePack(),
+    })
+}
+
+func (h *Handler) CreateRule(c *fiber.Ctx) error {
+    var rule detector.RuleDefinition
+    if err := c.BodyParser(&rule); err != nil {
+        return c.Status(400).JSON(models.APIResponse{
+            Success: false,
+            Error:   constants.ErrInvalidRequestBody,
+        })
+    }
+
+    if err := h.teamsService.CreateRule(rule); err != nil {
+        return c.Status(ruleErrorStatus(err)).JSON(models.APIResponse{
+            Success: false,
+            Error:   err.Error(),
+        })
+    }
+
+    return c.Status(201).JSON(models.APIResponse{
+        Success: true,
+        Data:    rule,
+        Message: constants.MsgRuleCreated,
+    })
+}
+
+func (h *Handler) UpdateRuleStatus(c *fiber.Ctx) error {
+    id := c.Params("id")
+    if id == "" {
+        return c.Status(400).JSON(models.APIResponse{
+            Success: false,
+            Error:   constants.ErrRuleIDRequired,
+        })
+    }
+
+    var request struct {
+        Enabled *bool `json:"enabled"`
+    }
+
+    if err := c.BodyParser(&request); err != nil || request.Enabled == nil {
+        return c.Status(400).JSON(models.APIResponse{
+            Success: false,
+            Error:   constants.ErrInvalidRequestBody,
+        })
+    }
+
+    if err := h.teamsService.SetRuleEnabled(id, *request.Enabled); err != nil {
+        return c.Status(ruleErrorStatus(err)).JSON(models.APIResponse{
+            Success: false,
+            Error:   err.Error(),
+        })
+    }
+
+    return c.JSON(models.APIResponse{
+        Success: true,
+        Message: constants.MsgRuleUpdated,
+    })
+}
+
+func (h *Handler) DeleteRule(c *fiber.Ctx) error {
+    id := c.Params("id")
+    if id == "" {
+        return c.Status(400).JSON(models.APIResponse{
+            Success: false,
+            Error:   constants.ErrRuleIDRequired,
+        })
+    }
+
+    if err := h.teamsService.DeleteRule(id); err != nil {
+        return c.Status(ruleErrorStatus(err)).JSON(models.APIResponse{
+            Success: false,
+            Error:   err.Error(),
+        })
+    }
+
+    return c.JSON(models.APIResponse{
+        Success: true,
+        Message: constants.MsgRuleDeleted,
+    })
+}
+
+// TestRule dry-runs a candidate rule against the testcases corpora without activating it
+func (h *Handler) TestRule(c *fiber.Ctx) error {
+    var rule detector.RuleDefinition
+    if err := c.BodyParser(&rule); err != nil {
+        return c.Status(400).JSON(models.APIResponse{
+            Success: false,
+            Error:   constants.ErrInvalidRequestBody,
+        })
+    }
+
+    report, err := h.teamsService.DryRunRule(rule)
+    if err != nil {
+        return c.Status(ruleErrorStatus(err)).JSON(models.APIResponse{
+            Success: false,
+            Error:   err.Error(),
+        })
+    }
+
+    return c.JSON(models.APIResponse{
+        Success: true,
+        Data:    report,
+        Message: constants.MsgRuleDryRun,
+    })
+}
+
+// ruleErrorStatus maps rule management errors to HTTP status codes
+func ruleErrorStatus(err error) int {
+    switch {
+    case errors.Is(err, detector.ErrRuleNotFound):
+        return 404
+    case errors.Is(err, detector.ErrRuleExists):
+        return 409
+    case errors.Is(err, services.ErrCorpusUnavailable):
+        return 500
+    default:
+        return 400
+    }
+}
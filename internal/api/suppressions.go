@@ -0,0 +1,23 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"stackguard-task/internal/models"
+)
+
+// GetSuppressions lists recently discarded matches with the suppression rule
+// that discarded each, newest first
+func (h *Handler) GetSuppressions(c *fiber.Ctx) error {
+    limit, err := strconv.Atoi(c.Query("limit", "50"))
+    if err != nil {
+        limit = 50
+    }
+
+    return c.JSON(models.APIResponse{
+        Success: true,
+        Data:    h.teamsService.GetSuppressions(limit),
+    })
+}
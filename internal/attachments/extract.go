@@ -0,0 +1,238 @@
+package attachments
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Limits bound the work spent on a single attachment, archives included
+type Limits struct {
+    MaxBytes      int64 // largest attachment that is fetched at all
+    MaxEntryBytes int64 // largest file read from inside an archive
+    MaxTotalBytes int64 // bytes unpacked from one attachment across all entries
+    MaxEntries    int   // archive entries looked at per attachment
+    MaxDepth      int   // archives nested inside archives that are opened
+}
+
+// DefaultLimits returns the limits used unless configured otherwise
+func DefaultLimits() Limits {
+    return Limits{
+        MaxBytes:      5 << 20,
+        MaxEntryBytes: 1 << 20,
+        MaxTotalBytes: 20 << 20,
+        MaxEntries:    1000,
+        MaxDepth:      2,
+    }
+}
+
+// ErrTooLarge is returned for attachments above Limits.MaxBytes
+var ErrTooLarge = errors.New("attachment exceeds size limit")
+
+// File is a piece of text found in an attachment
+type File struct {
+    Entry string // path inside the archive, empty for the attachment itself
+    Text  string
+}
+
+// Binary files are recognized by a NUL byte near the start
+const textSniffLength = 8000
+
+// Extract returns the text in an attachment: the attachment itself when it is
+// text, or the text files inside a zip or (gzipped) tar archive. Binary files,
+// entries over the limits and anything past the total budget are skipped.
+func Extract(name string, data []byte, limits Limits) ([]File, error) {
+    if int64(len(data)) > limits.MaxBytes {
+        return nil, ErrTooLarge
+    }
+    ex := &extractor{limits: limits}
+    if err := ex.extract("", name, data, 0); err != nil {
+        return ex.files, err
+    }
+    return ex.files, nil
+}
+
+// extractor tracks the budget of one Extract call across nested archives
+type extractor struct {
+    limits  Limits
+    files   []File
+    entries int
+    total   int64
+}
+
+func (ex *extractor) extract(entry, name string, data []byte, depth int) error {
+    kind := archiveKind(name, data)
+    if kind == "" {
+        if isText(data) {
+            ex.files = append(ex.files, File{Entry: entry, Text: string(data)})
+        }
+        return nil
+    }
+    if depth >= ex.limits.MaxDepth {
+        return nil
+    }
+
+    var err error
+    switch kind {
+    case "zip":
+        err = ex.extractZip(entry, data, depth)
+    case "tar":
+        err = ex.extractTar(entry, data, depth)
+    case "gzip":
+        // Compression is not nesting, the unpacked file (often a tar) is read at the same depth
+        var content []byte
+        if content, err = gunzip(data, ex.limits.MaxTotalBytes-ex.total); err == nil {
+            return ex.extract(entry, gunzippedName(name), content, depth)
+        }
+    }
+    if err != nil {
+        return fmt.Errorf("reading %s archive %s: %w", kind, entryLabel(entry, name), err)
+    }
+    return nil
+}
+
+func (ex *extractor) extractZip(entry string, data []byte, depth int) error {
+    reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+    if err != nil {
+        return err
+    }
+
+    for _, file := range reader.File {
+        if file.FileInfo().IsDir() {
+            continue
+        }
+        if !ex.takeEntry(int64(file.UncompressedSize64)) {
+            if ex.exhausted() {
+                break
+            }
+            continue
+        }
+        rc, err := file.Open()
+        if err != nil {
+            continue
+        }
+        // Sizes in the header can lie, never read more than the entry limit
+        content, err := readLimited(rc, ex.limits.MaxEntryBytes)
+        rc.Close()
+        if err != nil {
+            continue
+        }
+        ex.total += int64(len(content))
+        if err := ex.extract(path.Join(entry, file.Name), file.Name, content, depth+1); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (ex *extractor) extractTar(entry string, data []byte, depth int) error {
+    reader := tar.NewReader(bytes.NewReader(data))
+    for {
+        header, err := reader.Next()
+        if err == io.EOF {
+            return nil
+        }
+        if err != nil {
+            return err
+        }
+        if header.Typeflag != tar.TypeReg {
+            continue
+        }
+        if !ex.takeEntry(header.Size) {
+            if ex.exhausted() {
+                return nil
+            }
+            continue
+        }
+        content, err := readLimited(reader, ex.limits.MaxEntryBytes)
+        if err != nil {
+            continue
+        }
+        ex.total += int64(len(content))
+        if err := ex.extract(path.Join(entry, header.Name), header.Name, content, depth+1); err != nil {
+            return err
+        }
+    }
+}
+
+// takeEntry reports whether an entry of the given size fits the limits
+func (ex *extractor) takeEntry(size int64) bool {
+    if ex.exhausted() {
+        return false
+    }
+    ex.entries++
+    return size <= ex.limits.MaxEntryBytes && ex.total+size <= ex.limits.MaxTotalBytes
+}
+
+func (ex *extractor) exhausted() bool {
+    return ex.entries >= ex.limits.MaxEntries || ex.total >= ex.limits.MaxTotalBytes
+}
+
+// gunzip decompresses data, failing when it unpacks to more than limit bytes
+func gunzip(data []byte, limit int64) ([]byte, error) {
+    gz, err := gzip.NewReader(bytes.NewReader(data))
+    if err != nil {
+        return nil, err
+    }
+    defer gz.Close()
+    return readLimited(gz, limit)
+}
+
+// readLimited reads r fully, failing when it holds more than limit bytes
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+    content, err := io.ReadAll(io.LimitReader(r, limit+1))
+    if err != nil {
+        return nil, err
+    }
+    if int64(len(content)) > limit {
+        return nil, ErrTooLarge
+    }
+    return content, nil
+}
+
+// archiveKind identifies zip, tar and gzip files by their magic bytes, falling
+// back to the file name for tar files without a ustar header
+func archiveKind(name string, data []byte) string {
+    lower := strings.ToLower(name)
+    switch {
+    case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+        return "zip"
+    case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+        return "gzip"
+    case len(data) > 262 && string(data[257:262]) == "ustar":
+        return "tar"
+    case strings.HasSuffix(lower, ".tar"):
+        return "tar"
+    }
+    return ""
+}
+
+// gunzippedName is the name of a gzipped file once unpacked ("a.tgz" is "a.tar")
+func gunzippedName(name string) string {
+    lower := strings.ToLower(name)
+    switch {
+    case strings.HasSuffix(lower, ".tgz"):
+        return name[:len(name)-len(".tgz")] + ".tar"
+    case strings.HasSuffix(lower, ".gz"):
+        return name[:len(name)-len(".gz")]
+    }
+    return name
+}
+
+// isText treats content without NUL bytes near the start as text
+func isText(data []byte) bool {
+    return !bytes.Contains(data[:min(len(data), textSniffLength)], []byte{0})
+}
+
+func entryLabel(entry, name string) string {
+    if entry != "" {
+        return entry
+    }
+    return name
+}
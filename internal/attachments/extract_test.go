@@ -0,0 +1,156 @@
+package attachments
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// entry is a file to put in a test archive
+type entry struct {
+    name string
+    data []byte
+}
+
+func zipOf(t *testing.T, entries ...entry) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    w := zip.NewWriter(&buf)
+    for _, e := range entries {
+        f, err := w.Create(e.name)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if _, err := f.Write(e.data); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := w.Close(); err != nil {
+        t.Fatal(err)
+    }
+    return buf.Bytes()
+}
+
+func tarOf(t *testing.T, entries ...entry) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    w := tar.NewWriter(&buf)
+    for _, e := range entries {
+        if err := w.WriteHeader(&tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.data)), Typeflag: tar.TypeReg}); err != nil {
+            t.Fatal(err)
+        }
+        if _, err := w.Write(e.data); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := w.Close(); err != nil {
+        t.Fatal(err)
+    }
+    return buf.Bytes()
+}
+
+func gzipOf(t *testing.T, data []byte) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    w := gzip.NewWriter(&buf)
+    if _, err := w.Write(data); err != nil {
+        t.Fatal(err)
+    }
+    if err := w.Close(); err != nil {
+        t.Fatal(err)
+    }
+    return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+    env := entry{name: "config/.env", data: []byte("DB_PASSWORD=Vq8#kLm2pZ!x\n")}
+    binary := entry{name: "logo.png", data: []byte("\x89PNG\x00\x00binary")}
+
+    tests := []struct {
+        name        string
+        fileName    string
+        data        func(t *testing.T) []byte
+        wantEntries []string // entry paths of the text files found
+    }{
+        {name: "text file", fileName: "notes.txt", data: func(*testing.T) []byte { return []byte("hello") }, wantEntries: []string{""}},
+        {name: "binary file", fileName: "logo.png", data: func(*testing.T) []byte { return binary.data }},
+        {name: "zip", fileName: "bundle.zip", data: func(t *testing.T) []byte { return zipOf(t, env, binary) }, wantEntries: []string{"config/.env"}},
+        {name: "tar", fileName: "bundle.tar", data: func(t *testing.T) []byte { return tarOf(t, env) }, wantEntries: []string{"config/.env"}},
+        {name: "gzipped tar", fileName: "bundle.tgz", data: func(t *testing.T) []byte { return gzipOf(t, tarOf(t, env)) }, wantEntries: []string{"config/.env"}},
+        {
+            name:        "zip inside zip",
+            fileName:    "outer.zip",
+            data:        func(t *testing.T) []byte { return zipOf(t, entry{name: "inner.zip", data: zipOf(t, env)}) },
+            wantEntries: []string{"inner.zip/config/.env"},
+        },
+        {
+            name:     "nested deeper than the limit",
+            fileName: "outer.zip",
+            data: func(t *testing.T) []byte {
+                return zipOf(t, entry{name: "middle.zip", data: zipOf(t, entry{name: "inner.zip", data: zipOf(t, env)})})
+            },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            files, err := Extract(tt.fileName, tt.data(t), DefaultLimits())
+            if err != nil {
+                t.Fatalf("unexpected error: %v", err)
+            }
+            var entries []string
+            for _, file := range files {
+                entries = append(entries, file.Entry)
+            }
+            if !slices.Equal(entries, tt.wantEntries) {
+                t.Errorf("entries = %q, want %q", entries, tt.wantEntries)
+            }
+        })
+    }
+}
+
+func TestExtractLimits(t *testing.T) {
+    small := entry{name: "a.txt", data: []byte("small")}
+    big := entry{name: "big.txt", data: []byte(strings.Repeat("x", 2048))}
+
+    limits := DefaultLimits()
+    limits.MaxEntryBytes = 1024
+    files, err := Extract("bundle.zip", zipOf(t, big, small), limits)
+    if err != nil || len(files) != 1 || files[0].Entry != "a.txt" {
+        t.Errorf("oversized entry: files %+v, error %v", files, err)
+    }
+
+    limits = DefaultLimits()
+    limits.MaxEntries = 2
+    files, err = Extract("bundle.tar", tarOf(t, small, small, small, small), limits)
+    if err != nil || len(files) != 2 {
+        t.Errorf("entry cap: got %d files, error %v", len(files), err)
+    }
+
+    limits = DefaultLimits()
+    limits.MaxTotalBytes = 16
+    files, err = Extract("bundle.zip", zipOf(t, small, small, small, small), limits)
+    if err != nil || len(files) != 3 {
+        t.Errorf("total budget: got %d files, error %v", len(files), err)
+    }
+
+    limits = DefaultLimits()
+    limits.MaxBytes = 4
+    if _, err := Extract("notes.txt", []byte("hello"), limits); !errors.Is(err, ErrTooLarge) {
+        t.Errorf("oversized attachment: error %v, want ErrTooLarge", err)
+    }
+}
+
+// A gzip bomb fails instead of being unpacked past the budget
+func TestExtractGzipBomb(t *testing.T) {
+    limits := DefaultLimits()
+    limits.MaxTotalBytes = 1 << 10
+    if _, err := Extract("huge.txt.gz", gzipOf(t, bytes.Repeat([]byte("a"), 1<<20)), limits); !errors.Is(err, ErrTooLarge) {
+        t.Errorf("error %v, want ErrTooLarge", err)
+    }
+}
@@ -0,0 +1,144 @@
+package attachments
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"stackguard-task/internal/models"
+)
+
+// Fetcher retrieves the content of a message attachment, reading at most
+// maxBytes and failing with ErrTooLarge beyond that
+type Fetcher interface {
+    Fetch(attachment models.MessageAttachment, maxBytes int64) ([]byte, error)
+}
+
+// LocalFetcher reads attachments from a directory, a stand-in for Graph
+// downloads in tests and demos. contentUrl is a file:// URL or a path relative
+// to Root; paths outside Root, also through symlinks, are refused.
+type LocalFetcher struct {
+    Root string
+}
+
+func (lf LocalFetcher) Fetch(attachment models.MessageAttachment, maxBytes int64) ([]byte, error) {
+    if lf.Root == "" {
+        return nil, fmt.Errorf("no local attachment directory configured for %s", attachment.Name)
+    }
+
+    root, err := filepath.Abs(lf.Root)
+    if err != nil {
+        return nil, err
+    }
+    // Both sides are compared with symlinks resolved, so a link inside Root
+    // cannot point outside it
+    if root, err = filepath.EvalSymlinks(root); err != nil {
+        return nil, err
+    }
+    path := filepath.FromSlash(strings.TrimPrefix(attachment.ContentURL, "file://"))
+    if !filepath.IsAbs(path) {
+        path = filepath.Join(root, path)
+    }
+    if path, err = filepath.EvalSymlinks(path); err != nil {
+        return nil, err
+    }
+    if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+        return nil, fmt.Errorf("attachment %s is outside %s", attachment.Name, lf.Root)
+    }
+
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    return readLimited(file, maxBytes)
+}
+
+// HTTPFetcher downloads attachments from their contentUrl. Graph downloads
+// additionally need a bearer token, which Header can carry.
+type HTTPFetcher struct {
+    Client *http.Client
+    Header http.Header
+}
+
+func (hf HTTPFetcher) Fetch(attachment models.MessageAttachment, maxBytes int64) ([]byte, error) {
+    client := hf.Client
+    if client == nil {
+        client = &http.Client{Timeout: 30 * time.Second}
+    }
+
+    req, err := http.NewRequest(http.MethodGet, attachment.ContentURL, nil)
+    if err != nil {
+        return nil, err
+    }
+    for key, values := range hf.Header {
+        req.Header[key] = values
+    }
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return nil, fmt.Errorf("fetching %s: %s", attachment.Name, resp.Status)
+    }
+    if resp.ContentLength > maxBytes {
+        return nil, ErrTooLarge
+    }
+    return readLimited(resp.Body, maxBytes)
+}
+
+// URLFetcher picks a fetcher by the scheme of the attachment's contentUrl:
+// http(s) goes to HTTP, anything else to Local. A nil HTTP refuses downloads.
+type URLFetcher struct {
+    HTTP  Fetcher
+    Local Fetcher
+}
+
+// NewFetcher returns a fetcher for files under localRoot (refused when empty)
+// and, when allowHTTP is set, for http(s) URLs. Downloads are opt-in because
+// the URLs come from webhook payloads.
+func NewFetcher(localRoot string, allowHTTP bool) *URLFetcher {
+    fetcher := &URLFetcher{Local: LocalFetcher{Root: localRoot}}
+    if allowHTTP {
+        fetcher.HTTP = HTTPFetcher{}
+    }
+    return fetcher
+}
+
+func (uf *URLFetcher) Fetch(attachment models.MessageAttachment, maxBytes int64) ([]byte, error) {
+    parsed, err := url.Parse(attachment.ContentURL)
+    if err != nil {
+        return nil, fmt.Errorf("attachment %s: %w", attachment.Name, err)
+    }
+    switch parsed.Scheme {
+    case "http", "https":
+        if uf.HTTP == nil {
+            return nil, fmt.Errorf("attachment %s: HTTP downloads are disabled", attachment.Name)
+        }
+        return uf.HTTP.Fetch(attachment, maxBytes)
+    default:
+        return uf.Local.Fetch(attachment, maxBytes)
+    }
+}
+
+// Read returns the text in an attachment. Inline content (code snippets,
+// cards) is used as is, anything else is fetched first.
+func Read(fetcher Fetcher, attachment models.MessageAttachment, limits Limits) ([]File, error) {
+    data := []byte(attachment.Content)
+    if attachment.Content == "" {
+        if attachment.ContentURL == "" {
+            return nil, nil
+        }
+        var err error
+        if data, err = fetcher.Fetch(attachment, limits.MaxBytes); err != nil {
+            return nil, err
+        }
+    }
+    return Extract(attachment.Name, data, limits)
+}
@@ -0,0 +1,105 @@
+package attachments
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"stackguard-task/internal/models"
+)
+
+func TestLocalFetcher(t *testing.T) {
+    root := t.TempDir()
+    if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte("hello"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    outside := t.TempDir()
+    if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("outside"), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.Symlink(filepath.Join(outside, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.Symlink(outside, filepath.Join(root, "linkdir")); err != nil {
+        t.Fatal(err)
+    }
+    if err := os.Symlink(filepath.Join(root, "notes.txt"), filepath.Join(root, "alias.txt")); err != nil {
+        t.Fatal(err)
+    }
+    fetcher := LocalFetcher{Root: root}
+
+    tests := []struct {
+        name       string
+        contentURL string
+        maxBytes   int64
+        want       string
+        wantErr    bool
+    }{
+        {name: "relative path", contentURL: "notes.txt", maxBytes: 100, want: "hello"},
+        {name: "file URL", contentURL: "file://" + filepath.ToSlash(filepath.Join(root, "notes.txt")), maxBytes: 100, want: "hello"},
+        {name: "outside the root", contentURL: "../notes.txt", maxBytes: 100, wantErr: true},
+        {name: "absolute path outside the root", contentURL: "/etc/passwd", maxBytes: 100, wantErr: true},
+        {name: "symlink to a file outside the root", contentURL: "link.txt", maxBytes: 100, wantErr: true},
+        {name: "through a symlinked directory", contentURL: "linkdir/secret.txt", maxBytes: 100, wantErr: true},
+        {name: "symlink inside the root", contentURL: "alias.txt", maxBytes: 100, want: "hello"},
+        {name: "missing file", contentURL: "missing.txt", maxBytes: 100, wantErr: true},
+        {name: "over the size limit", contentURL: "notes.txt", maxBytes: 4, wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            data, err := fetcher.Fetch(models.MessageAttachment{Name: "notes.txt", ContentURL: tt.contentURL}, tt.maxBytes)
+            if (err != nil) != tt.wantErr || string(data) != tt.want {
+                t.Errorf("Fetch = %q, %v", data, err)
+            }
+        })
+    }
+
+    if _, err := (LocalFetcher{}).Fetch(models.MessageAttachment{Name: "notes.txt", ContentURL: "notes.txt"}, 100); err == nil {
+        t.Error("fetched without a root directory")
+    }
+}
+
+func TestURLFetcher(t *testing.T) {
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/notes.txt" {
+            http.NotFound(w, r)
+            return
+        }
+        w.Write([]byte("hello"))
+    }))
+    defer server.Close()
+
+    attachment := models.MessageAttachment{Name: "notes.txt", ContentURL: server.URL + "/notes.txt"}
+    if _, err := NewFetcher("", false).Fetch(attachment, 100); err == nil {
+        t.Error("downloaded with HTTP disabled")
+    }
+
+    data, err := NewFetcher("", true).Fetch(attachment, 100)
+    if err != nil || string(data) != "hello" {
+        t.Errorf("Fetch = %q, %v", data, err)
+    }
+    if _, err := NewFetcher("", true).Fetch(attachment, 4); !errors.Is(err, ErrTooLarge) {
+        t.Errorf("error %v, want ErrTooLarge", err)
+    }
+    missing := models.MessageAttachment{Name: "missing.txt", ContentURL: server.URL + "/missing.txt"}
+    if _, err := NewFetcher("", true).Fetch(missing, 100); err == nil {
+        t.Error("no error for a 404")
+    }
+}
+
+func TestReadInlineContent(t *testing.T) {
+    // Inline content is used as is, the fetcher is never asked
+    files, err := Read(LocalFetcher{}, models.MessageAttachment{Name: "snippet", Content: "token: abc"}, DefaultLimits())
+    if err != nil || len(files) != 1 || files[0].Text != "token: abc" {
+        t.Errorf("Read = %+v, %v", files, err)
+    }
+
+    files, err = Read(LocalFetcher{}, models.MessageAttachment{Name: "card"}, DefaultLimits())
+    if err != nil || len(files) != 0 {
+        t.Errorf("attachment without content: %+v, %v", files, err)
+    }
+}
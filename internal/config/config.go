@@ -17,6 +17,20 @@ type Config struct {
     MonitoringInterval  int
     MockMode            bool
     LogLevel            string
+    RulesFile           string
+    RulesReloadInterval int
+    TestcasesDir        string
+    EntropyScan         bool
+    SplitWindowMessages int
+    SplitWindowSeconds  int
+    AttachmentScan      bool
+    AttachmentDir       string
+    AttachmentHTTPFetch bool
+    AttachmentMaxBytes  int64
+    FeedbackModel       string
+    ScoringProfiles     string
+    KeywordsDir         string
+    SuppressionFile     string
 }
 
 func Load() *Config {
@@ -49,6 +63,71 @@ func Load() *Config {
 
     cfg.LogLevel = getOptionalEnv("LOG_LEVEL", "info")
 
+    // Empty means the built-in default rule pack is used
+    cfg.RulesFile = getOptionalEnv("RULES_FILE", "")
+
+    // How often (seconds) the rule file is checked for changes, 0 disables watching
+    reloadStr := getOptionalEnv("RULES_RELOAD_INTERVAL", "10")
+    cfg.RulesReloadInterval, err = strconv.Atoi(reloadStr)
+    if err != nil {
+        log.Fatalf("Configuration error: RULES_RELOAD_INTERVAL '%s' is not a valid integer: %v", reloadStr, err)
+    }
+
+    // Labelled corpora used by rule dry-runs
+    cfg.TestcasesDir = getOptionalEnv("TESTCASES_DIR", "./testcases")
+
+    // Entropy-only discovery of unknown token formats (reported as LOW severity)
+    entropyStr := getOptionalEnv("ENTROPY_SCAN", "false")
+    cfg.EntropyScan, err = strconv.ParseBool(entropyStr)
+    if err != nil {
+        log.Fatalf("Configuration error: ENTROPY_SCAN '%s' is not a valid boolean (true/false): %v", entropyStr, err)
+    }
+
+    // Secrets split over consecutive messages: how many earlier messages per
+    // channel and user are kept, and for how long (0 messages disables it)
+    splitMessagesStr := getOptionalEnv("SPLIT_WINDOW_MESSAGES", "2")
+    cfg.SplitWindowMessages, err = strconv.Atoi(splitMessagesStr)
+    if err != nil {
+        log.Fatalf("Configuration error: SPLIT_WINDOW_MESSAGES '%s' is not a valid integer: %v", splitMessagesStr, err)
+    }
+    splitSecondsStr := getOptionalEnv("SPLIT_WINDOW_SECONDS", "120")
+    cfg.SplitWindowSeconds, err = strconv.Atoi(splitSecondsStr)
+    if err != nil {
+        log.Fatalf("Configuration error: SPLIT_WINDOW_SECONDS '%s' is not a valid integer: %v", splitSecondsStr, err)
+    }
+
+    // Attachments: inline snippets are always available, files are read from
+    // ATTACHMENT_DIR (the local stand-in for Graph downloads) or, opt-in, over HTTP
+    attachmentScanStr := getOptionalEnv("ATTACHMENT_SCAN", "true")
+    cfg.AttachmentScan, err = strconv.ParseBool(attachmentScanStr)
+    if err != nil {
+        log.Fatalf("Configuration error: ATTACHMENT_SCAN '%s' is not a valid boolean (true/false): %v", attachmentScanStr, err)
+    }
+    cfg.AttachmentDir = getOptionalEnv("ATTACHMENT_DIR", "")
+    httpFetchStr := getOptionalEnv("ATTACHMENT_HTTP_FETCH", "false")
+    cfg.AttachmentHTTPFetch, err = strconv.ParseBool(httpFetchStr)
+    if err != nil {
+        log.Fatalf("Configuration error: ATTACHMENT_HTTP_FETCH '%s' is not a valid boolean (true/false): %v", httpFetchStr, err)
+    }
+    maxBytesStr := getOptionalEnv("ATTACHMENT_MAX_BYTES", "5242880")
+    cfg.AttachmentMaxBytes, err = strconv.ParseInt(maxBytesStr, 10, 64)
+    if err != nil {
+        log.Fatalf("Configuration error: ATTACHMENT_MAX_BYTES '%s' is not a valid integer: %v", maxBytesStr, err)
+    }
+
+    // Calibration learned from analyst feedback, loaded at startup and
+    // overwritten on retrain. Empty keeps retrained scoring in memory only.
+    cfg.FeedbackModel = getOptionalEnv("FEEDBACK_MODEL", "")
+
+    // Scoring profiles per rule, channel and team; empty uses the built-in default
+    cfg.ScoringProfiles = getOptionalEnv("SCORING_PROFILES", "")
+
+    // Extra or replacement context keyword dictionaries, one YAML file per language
+    cfg.KeywordsDir = getOptionalEnv("KEYWORDS_DIR", "")
+
+    // Suppression vocabulary replacing the built-in one; empty uses the built-in
+    cfg.SuppressionFile = getOptionalEnv("SUPPRESSION_FILE", "")
+
     return cfg
 }
 
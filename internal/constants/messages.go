@@ -41,6 +41,13 @@ const (
     MsgSecretDetectionTest  = "Secret detection test completed"
     MsgMessageProcessed     = "Message processed successfully"
     MsgHealthy              = "Service is healthy"
+    MsgRulesReloaded        = "Detection rules reloaded successfully"
+    MsgRuleCreated          = "Rule created successfully"
+    MsgRuleUpdated          = "Rule status updated successfully"
+    MsgRuleDeleted          = "Rule deleted successfully"
+    MsgRuleDryRun           = "Rule dry-run completed"
+    MsgFeedbackRetrained    = "Scoring retrained from analyst feedback"
+    MsgNoFeedbackModel      = "No feedback model trained yet, default scoring is in use"
     
     // Error messages
     ErrInvalidRequestBody    = "Invalid request body"
@@ -50,6 +57,9 @@ const (
     ErrInvalidStatus         = "Invalid status. Must be: new, acknowledged, resolved, or false_positive"
     ErrDetectionNotFound     = "Detection not found"
     ErrInvalidWebhookPayload = "Invalid webhook payload"
+    ErrRulesReloadFailed     = "Rule reload failed, previous rules are still active"
+    ErrRuleIDRequired        = "Rule ID is required"
+    ErrModelSaveFailed       = "Retrained scoring is active but could not be saved"
 )
 
 // GetSeverityEmoji returns the appropriate emoji for a severity level
@@ -17,8 +17,24 @@ const (
     DetectionsByChannelRoute  = "/detections/channel/:channelId"
     DetectionsByStatusRoute   = "/detections/status/:status"
     DetectionStatusRoute      = "/detections/:id/status"
+    DetectionExplainRoute     = "/detections/:id/explain"
     ClearDetectionsRoute      = "/detections/clear"
     
+    // Rule management routes
+    RulesRoute                = "/rules"
+    RuleRoute                 = "/rules/:id"
+    RuleStatusRoute           = "/rules/:id/status"
+    RulesTestRoute            = "/rules/test"
+    RulesReloadRoute          = "/rules/reload"
+    
+    // Analyst feedback routes
+    FeedbackSamplesRoute      = "/feedback/samples"
+    FeedbackModelRoute        = "/feedback/model"
+    FeedbackRetrainRoute      = "/feedback/retrain"
+    
+    // Suppression audit routes
+    SuppressionsRoute         = "/suppressions"
+    
     // Webhook routes
     TeamsWebhookRoute         = "/webhook/teams"
     TestDetectionRoute        = "/test/detect"
@@ -0,0 +1,84 @@
+package corpus
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"stackguard-task/internal/models"
+)
+
+// Default corpus files shipped in the testcases directory
+const (
+    ShouldBeDetectedFile    = "should_be_detected.json"
+    ShouldNotBeDetectedFile = "should_not_be_detected.json"
+)
+
+// TestCase is one labelled sample message from the testcases corpora
+type TestCase struct {
+    Description        string      `json:"description"`
+    RequestBody        RequestBody `json:"request_body"`
+    ExpectedDetections []string    `json:"expected_detections"`
+
+    // Source is the corpus file the case was loaded from
+    Source string `json:"-"`
+}
+
+// RequestBody mirrors the body accepted by the test detection endpoint
+type RequestBody struct {
+    Text      string `json:"text"`
+    ChannelID string `json:"channelId"`
+    UserName  string `json:"userName"`
+}
+
+// Load reads a single corpus file
+func Load(path string) ([]TestCase, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("reading corpus %s: %w", path, err)
+    }
+
+    var cases []TestCase
+    if err := json.Unmarshal(data, &cases); err != nil {
+        return nil, fmt.Errorf("parsing corpus %s: %w", path, err)
+    }
+
+    source := filepath.Base(path)
+    for i := range cases {
+        cases[i].Source = source
+    }
+    return cases, nil
+}
+
+// LoadDir reads the positive and negative corpora from a testcases directory
+func LoadDir(dir string) ([]TestCase, error) {
+    var all []TestCase
+    for _, name := range []string{ShouldBeDetectedFile, ShouldNotBeDetectedFile} {
+        cases, err := Load(filepath.Join(dir, name))
+        if err != nil {
+            return nil, err
+        }
+        all = append(all, cases...)
+    }
+    return all, nil
+}
+
+// Message builds the Teams message the scanner sees for this case
+func (tc TestCase) Message(index int) models.TeamsMessage {
+    message := models.TeamsMessage{
+        ID:        fmt.Sprintf("corpus-%s-%d", tc.Source, index),
+        CreatedAt: time.Now(),
+        ChannelID: tc.RequestBody.ChannelID,
+        TeamID:    "corpus",
+        Body: models.MessageBody{
+            ContentType: "text",
+            Content:     tc.RequestBody.Text,
+        },
+    }
+    message.From.User.ID = "corpus-user"
+    message.From.User.DisplayName = tc.RequestBody.UserName
+    message.From.User.UserType = "user"
+    return message
+}
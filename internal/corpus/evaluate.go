@@ -0,0 +1,222 @@
+package corpus
+
+import (
+	"sort"
+
+	"stackguard-task/internal/detector"
+)
+
+// NoDetection labels the "nothing expected" / "nothing detected" row and column of the confusion table
+const NoDetection = "(none)"
+
+// RuleMetrics holds the outcome counts for a single secret type
+type RuleMetrics struct {
+    SecretType     string  `json:"secretType"`
+    TruePositives  int     `json:"truePositives"`
+    FalsePositives int     `json:"falsePositives"`
+    FalseNegatives int     `json:"falseNegatives"`
+    TrueNegatives  int     `json:"trueNegatives"`
+    Precision      float64 `json:"precision"`
+    Recall         float64 `json:"recall"`
+}
+
+// CaseResult records what the scanner found for one corpus case
+type CaseResult struct {
+    Description string   `json:"description"`
+    Source      string   `json:"source"`
+    Expected    []string `json:"expected"`
+    Detected    []string `json:"detected"`
+    Missed      []string `json:"missed,omitempty"`
+    Unexpected  []string `json:"unexpected,omitempty"`
+}
+
+// Passed reports whether the scanner output matched the labels exactly
+func (cr CaseResult) Passed() bool {
+    return len(cr.Missed) == 0 && len(cr.Unexpected) == 0
+}
+
+// EvaluationReport aggregates scanner performance over a corpus
+type EvaluationReport struct {
+    RulesVersion string                    `json:"rulesVersion"`
+    Cases        []CaseResult              `json:"cases"`
+    Rules        []RuleMetrics             `json:"rules"`
+    Overall      RuleMetrics               `json:"overall"`
+    Confusion    map[string]map[string]int `json:"confusion"` // expected -> detected -> count
+}
+
+// Evaluate runs every case through the scanner and compares detected secret
+// types against expected_detections
+func Evaluate(scanner *detector.SecretScanner, cases []TestCase) EvaluationReport {
+    report := EvaluationReport{
+        RulesVersion: scanner.RulesVersion(),
+        Confusion:    make(map[string]map[string]int),
+    }
+
+    metrics := make(map[string]*RuleMetrics)
+    metricFor := func(secretType string) *RuleMetrics {
+        if m, ok := metrics[secretType]; ok {
+            return m
+        }
+        m := &RuleMetrics{SecretType: secretType}
+        metrics[secretType] = m
+        return m
+    }
+
+    // Every rule gets a row even if the corpus never mentions it
+    for _, rule := range scanner.RulePack().Rules {
+        if !rule.Disabled {
+            metricFor(rule.Name)
+        }
+    }
+
+    results := make([]struct{ expected, detected map[string]bool }, len(cases))
+
+    for i, tc := range cases {
+        expected := make(map[string]bool)
+        for _, secretType := range tc.ExpectedDetections {
+            expected[secretType] = true
+            metricFor(secretType)
+        }
+
+        detected := make(map[string]bool)
+        for _, detection := range scanner.ScanMessage(tc.Message(i)) {
+            detected[detection.SecretType] = true
+            metricFor(detection.SecretType)
+        }
+
+        results[i].expected, results[i].detected = expected, detected
+
+        result := CaseResult{
+            Description: tc.Description,
+            Source:      tc.Source,
+            Expected:    sortedSet(expected),
+            Detected:    sortedSet(detected),
+        }
+        for secretType := range expected {
+            if detected[secretType] {
+                report.addConfusion(secretType, secretType)
+            } else {
+                result.Missed = append(result.Missed, secretType)
+            }
+        }
+        for secretType := range detected {
+            if !expected[secretType] {
+                result.Unexpected = append(result.Unexpected, secretType)
+            }
+        }
+        sort.Strings(result.Missed)
+        sort.Strings(result.Unexpected)
+
+        // Pair up misses with unexpected detections, they are usually the same
+        // secret attributed to the wrong rule
+        switch {
+        case len(result.Missed) > 0 && len(result.Unexpected) > 0:
+            for _, missed := range result.Missed {
+                for _, unexpected := range result.Unexpected {
+                    report.addConfusion(missed, unexpected)
+                }
+            }
+        case len(result.Missed) > 0:
+            for _, missed := range result.Missed {
+                report.addConfusion(missed, NoDetection)
+            }
+        case len(result.Unexpected) > 0:
+            for _, unexpected := range result.Unexpected {
+                report.addConfusion(NoDetection, unexpected)
+            }
+        case len(expected) == 0:
+            report.addConfusion(NoDetection, NoDetection)
+        }
+
+        report.Cases = append(report.Cases, result)
+    }
+
+    // Per-type counts need the full type list, so they are tallied afterwards
+    for _, result := range results {
+        for secretType, m := range metrics {
+            switch {
+            case result.expected[secretType] && result.detected[secretType]:
+                m.TruePositives++
+            case result.detected[secretType]:
+                m.FalsePositives++
+            case result.expected[secretType]:
+                m.FalseNegatives++
+            default:
+                m.TrueNegatives++
+            }
+        }
+    }
+
+    report.Overall.SecretType = "overall"
+    for _, m := range metrics {
+        m.computeRates()
+        report.Rules = append(report.Rules, *m)
+
+        report.Overall.TruePositives += m.TruePositives
+        report.Overall.FalsePositives += m.FalsePositives
+        report.Overall.FalseNegatives += m.FalseNegatives
+        report.Overall.TrueNegatives += m.TrueNegatives
+    }
+    report.Overall.computeRates()
+
+    sort.Slice(report.Rules, func(i, j int) bool {
+        return report.Rules[i].SecretType < report.Rules[j].SecretType
+    })
+
+    return report
+}
+
+// ConfusionCell is one non-zero cell of the confusion table
+type ConfusionCell struct {
+    Expected string `json:"expected"`
+    Detected string `json:"detected"`
+    Count    int    `json:"count"`
+}
+
+// ConfusionCells lists the non-zero cells of the confusion table, sorted by
+// expected then detected type
+func (er EvaluationReport) ConfusionCells() []ConfusionCell {
+    var cells []ConfusionCell
+    for expected, row := range er.Confusion {
+        for detected, count := range row {
+            if count > 0 {
+                cells = append(cells, ConfusionCell{Expected: expected, Detected: detected, Count: count})
+            }
+        }
+    }
+    sort.Slice(cells, func(i, j int) bool {
+        if cells[i].Expected != cells[j].Expected {
+            return cells[i].Expected < cells[j].Expected
+        }
+        return cells[i].Detected < cells[j].Detected
+    })
+    return cells
+}
+
+func (er *EvaluationReport) addConfusion(expected, detected string) {
+    if er.Confusion[expected] == nil {
+        er.Confusion[expected] = make(map[string]int)
+    }
+    er.Confusion[expected][detected]++
+}
+
+// computeRates fills in precision and recall; an empty denominator counts as perfect
+func (rm *RuleMetrics) computeRates() {
+    rm.Precision = 1.0
+    if rm.TruePositives+rm.FalsePositives > 0 {
+        rm.Precision = float64(rm.TruePositives) / float64(rm.TruePositives+rm.FalsePositives)
+    }
+    rm.Recall = 1.0
+    if rm.TruePositives+rm.FalseNegatives > 0 {
+        rm.Recall = float64(rm.TruePositives) / float64(rm.TruePositives+rm.FalseNegatives)
+    }
+}
+
+func sortedSet(set map[string]bool) []string {
+    values := make([]string, 0, len(set))
+    for value := range set {
+        values = append(values, value)
+    }
+    sort.Strings(values)
+    return values
+}
@@ -0,0 +1,121 @@
+package corpus
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"stackguard-task/internal/detector"
+)
+
+const fixtureRulePack = `
+version: "0.1.0"
+rules:
+  - id: internal-api-token
+    name: Internal API Token
+    pattern: 'itk_[A-Za-z0-9]{32}'
+    severity: HIGH
+    specificity: 0.95
+    keywords: [itk, token]
+  - id: internal-api-secret
+    name: Internal API Secret
+    pattern: 'its_[A-Za-z0-9]{40}'
+    severity: HIGH
+    specificity: 0.95
+    keywords: [its, secret]
+  - id: internal-webhook
+    name: Internal Webhook
+    pattern: 'iwh_[A-Za-z0-9]{24}'
+    severity: MEDIUM
+    specificity: 0.95
+`
+
+func evaluateFixture(t *testing.T) EvaluationReport {
+    t.Helper()
+    pack, err := detector.ParseRulePack([]byte(fixtureRulePack))
+    if err != nil {
+        t.Fatal(err)
+    }
+    scanner, err := detector.NewSecretScannerFromPack(pack)
+    if err != nil {
+        t.Fatal(err)
+    }
+    cases, err := LoadDir("testdata")
+    if err != nil {
+        t.Fatal(err)
+    }
+    return Evaluate(scanner, cases)
+}
+
+func TestEvaluateMetrics(t *testing.T) {
+    report := evaluateFixture(t)
+
+    tests := []RuleMetrics{
+        {SecretType: "Internal API Secret", FalsePositives: 1, FalseNegatives: 1, TrueNegatives: 3, Precision: 0, Recall: 0},
+        {SecretType: "Internal API Token", TruePositives: 1, FalsePositives: 1, FalseNegatives: 1, TrueNegatives: 2, Precision: 0.5, Recall: 0.5},
+        // Never expected nor detected: an empty denominator counts as perfect
+        {SecretType: "Internal Webhook", TrueNegatives: 5, Precision: 1, Recall: 1},
+        {SecretType: "overall", TruePositives: 1, FalsePositives: 2, FalseNegatives: 2, TrueNegatives: 10, Precision: 1.0 / 3, Recall: 1.0 / 3},
+    }
+
+    rules := append(report.Rules, report.Overall)
+    if len(rules) != len(tests) {
+        t.Fatalf("metrics for %d types, want %d: %+v", len(rules)-1, len(tests)-1, rules)
+    }
+    for i, want := range tests {
+        t.Run(want.SecretType, func(t *testing.T) {
+            got := rules[i]
+            if got.SecretType != want.SecretType ||
+                got.TruePositives != want.TruePositives || got.FalsePositives != want.FalsePositives ||
+                got.FalseNegatives != want.FalseNegatives || got.TrueNegatives != want.TrueNegatives ||
+                math.Abs(got.Precision-want.Precision) > 1e-9 || math.Abs(got.Recall-want.Recall) > 1e-9 {
+                t.Errorf("metrics = %+v, want %+v", got, want)
+            }
+        })
+    }
+}
+
+func TestEvaluateCases(t *testing.T) {
+    report := evaluateFixture(t)
+
+    tests := []struct {
+        description    string
+        wantMissed     []string
+        wantUnexpected []string
+    }{
+        {description: "token"},
+        {description: "secret reported as the wrong type", wantMissed: []string{"Internal API Token"}, wantUnexpected: []string{"Internal API Secret"}},
+        {description: "secret missed", wantMissed: []string{"Internal API Secret"}},
+        {description: "prose"},
+        {description: "token labelled as harmless", wantUnexpected: []string{"Internal API Token"}},
+    }
+
+    if len(report.Cases) != len(tests) {
+        t.Fatalf("%d case results, want %d", len(report.Cases), len(tests))
+    }
+    for i, tt := range tests {
+        t.Run(tt.description, func(t *testing.T) {
+            result := report.Cases[i]
+            if result.Description != tt.description || !slices.Equal(result.Missed, tt.wantMissed) || !slices.Equal(result.Unexpected, tt.wantUnexpected) {
+                t.Errorf("result = %+v", result)
+            }
+            if result.Passed() != (len(tt.wantMissed)+len(tt.wantUnexpected) == 0) {
+                t.Errorf("Passed = %v", result.Passed())
+            }
+        })
+    }
+}
+
+func TestConfusionCells(t *testing.T) {
+    report := evaluateFixture(t)
+    want := []ConfusionCell{
+        {Expected: NoDetection, Detected: NoDetection, Count: 1},
+        {Expected: NoDetection, Detected: "Internal API Token", Count: 1},
+        {Expected: "Internal API Secret", Detected: NoDetection, Count: 1},
+        {Expected: "Internal API Token", Detected: "Internal API Secret", Count: 1},
+        {Expected: "Internal API Token", Detected: "Internal API Token", Count: 1},
+    }
+    if got := report.ConfusionCells(); !slices.Equal(got, want) {
+        t.Errorf("cells = %+v, want %+v", got, want)
+    }
+}
@@ -0,0 +1,22 @@
+package detector
+
+import (
+	"stackguard-task/internal/models"
+)
+
+// ScanAttachmentText scans text taken from an attachment of msg; entry is the
+// path of the file inside an archive attachment, empty otherwise. Detections
+// are attributed to the attachment and their offsets, lines and columns refer
+// to text rather than the message body.
+func (s *SecretScanner) ScanAttachmentText(msg models.TeamsMessage, attachment, entry, text string) []models.SecretDetection {
+    msg.Body = models.MessageBody{ContentType: "text", Content: text}
+    detections := s.ScanMessage(msg)
+
+    for i := range detections {
+        detections[i].Attachment = attachment
+        detections[i].AttachmentEntry = entry
+        // The same secret at the same offset of two files is two findings
+        detections[i].ID = generateDetectionID(msg.ID+"/"+attachment+"/"+entry, detections[i].FullValue, detections[i].StartOffset)
+    }
+    return detections
+}
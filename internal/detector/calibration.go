@@ -0,0 +1,184 @@
+package detector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Factor names, in the order their weights are applied
+var confidenceFactorNames = []string{"specificity", "entropy", "context", "length", "composition"}
+
+// Weights used until a calibration has been trained
+var defaultFactorWeights = map[string]float64{
+    "specificity": 0.3,
+    "entropy":     0.25,
+    "context":     0.2,
+    "length":      0.15,
+    "composition": 0.1,
+}
+
+// DefaultFactorWeights returns a copy of the built-in factor weights
+func DefaultFactorWeights() map[string]float64 {
+    weights := make(map[string]float64, len(defaultFactorWeights))
+    for name, weight := range defaultFactorWeights {
+        weights[name] = weight
+    }
+    return weights
+}
+
+// ConfidenceFactorNames returns the names of the weighted confidence factors
+func ConfidenceFactorNames() []string {
+    return append([]string(nil), confidenceFactorNames...)
+}
+
+// Calibration is scoring learned from analyst feedback: factor weights plus
+// log-odds shifts for rules, channels and context tokens that analysts
+// marked as false positives more or less often than average
+type Calibration struct {
+    TrainedAt time.Time          `json:"trainedAt"`
+    Samples   int                `json:"samples"`
+    Weights   map[string]float64 `json:"weights"`
+    Rules     map[string]float64 `json:"rules,omitempty"`
+    Channels  map[string]float64 `json:"channels,omitempty"`
+    Tokens    map[string]float64 `json:"tokens,omitempty"`
+}
+
+// The combined feedback shift is capped so feedback alone cannot flip a
+// near-certain detection
+const maxFeedbackShift = 2.0
+
+// LoadCalibration reads a calibration written by SaveCalibration
+func LoadCalibration(path string) (*Calibration, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("reading calibration %s: %w", path, err)
+    }
+
+    var calibration Calibration
+    if err := json.Unmarshal(data, &calibration); err != nil {
+        return nil, fmt.Errorf("parsing calibration %s: %w", path, err)
+    }
+    if err := calibration.validate(); err != nil {
+        return nil, fmt.Errorf("calibration %s: %w", path, err)
+    }
+    return &calibration, nil
+}
+
+// validate checks the weights as a profile's are checked: every factor is
+// known and weighted, none is negative and they do not sum to zero, which
+// would leave the weighted score undefined. All problems are reported together.
+func (c *Calibration) validate() error {
+    var errs []string
+    var total float64
+    for _, name := range confidenceFactorNames {
+        weight, ok := c.Weights[name]
+        switch {
+        case !ok:
+            errs = append(errs, fmt.Sprintf("missing weight for %s", name))
+        case weight < 0:
+            errs = append(errs, fmt.Sprintf("negative weight for %s", name))
+        default:
+            total += weight
+        }
+    }
+
+    var unknown []string
+    for name := range c.Weights {
+        if _, ok := defaultFactorWeights[name]; !ok {
+            unknown = append(unknown, name)
+        }
+    }
+    sort.Strings(unknown)
+    for _, name := range unknown {
+        errs = append(errs, fmt.Sprintf("unknown factor %q (must be one of %s)", name, strings.Join(confidenceFactorNames, ", ")))
+    }
+
+    if total <= 0 {
+        errs = append(errs, "weights must not all be zero")
+    }
+    if len(errs) > 0 {
+        return errors.New(strings.Join(errs, "; "))
+    }
+    return nil
+}
+
+// SaveCalibration writes a calibration as indented JSON
+func SaveCalibration(path string, calibration *Calibration) error {
+    data, err := json.MarshalIndent(calibration, "", "  ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// SetCalibration switches subsequent scans to learned scoring, nil restores the defaults
+func (s *SecretScanner) SetCalibration(calibration *Calibration) {
+    s.calibration.Store(calibration)
+}
+
+// Calibration returns the learned scoring in use, nil when scoring uses the defaults
+func (s *SecretScanner) Calibration() *Calibration {
+    return s.calibration.Load()
+}
+
+// newConfidenceCalculator returns a calculator using the active calibration
+func (s *SecretScanner) newConfidenceCalculator() *ConfidenceCalculator {
+    calc := NewConfidenceCalculator()
+    calc.calibration = s.calibration.Load()
+    return calc
+}
+
+// Weight returns the weight of a factor, falling back to the default
+func (c *Calibration) Weight(name string) float64 {
+    if c != nil {
+        if weight, ok := c.Weights[name]; ok {
+            return weight
+        }
+    }
+    return defaultFactorWeights[name]
+}
+
+// FeedbackShift is the log-odds shift for a match of ruleID in channelID with
+// the given context tokens, capped to maxFeedbackShift either way
+func (c *Calibration) FeedbackShift(ruleID, channelID string, tokens []string) float64 {
+    if c == nil {
+        return 0
+    }
+    shift := c.Rules[ruleID] + c.Channels[channelID]
+    for _, token := range tokens {
+        shift += c.Tokens[token]
+    }
+    return math.Max(-maxFeedbackShift, math.Min(shift, maxFeedbackShift))
+}
+
+// ApplyShift moves a score by a log-odds shift
+func ApplyShift(score, shift float64) float64 {
+    if shift == 0 {
+        return score
+    }
+    p := math.Max(0.01, math.Min(score, 0.99))
+    return 1 / (1 + math.Exp(-(math.Log(p/(1-p)) + shift)))
+}
+
+var contextTokenPattern = regexp.MustCompile(`[a-z]{3,}`)
+
+// ContextTokens returns the distinct lowercase words of a detection context,
+// the features feedback is learned over
+func ContextTokens(context string) []string {
+    seen := make(map[string]bool)
+    var tokens []string
+    for _, token := range contextTokenPattern.FindAllString(strings.ToLower(context), -1) {
+        if !seen[token] {
+            seen[token] = true
+            tokens = append(tokens, token)
+        }
+    }
+    return tokens
+}
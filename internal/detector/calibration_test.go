@@ -0,0 +1,121 @@
+package detector
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCalibration(t *testing.T) {
+    tests := []struct {
+        name    string
+        data    string
+        wantErr string // "" when the file is accepted
+    }{
+        {
+            name: "trained weights",
+            data: `{"weights": {"specificity": 0.4, "entropy": 0.2, "context": 0.2, "length": 0.1, "composition": 0.1}, "rules": {"generic-api-key": -0.5}}`,
+        },
+        {
+            name: "one factor unweighted",
+            data: `{"weights": {"specificity": 1, "entropy": 0, "context": 0, "length": 0, "composition": 0}}`,
+        },
+        {
+            name:    "all weights zero",
+            data:    `{"weights": {"specificity": 0, "entropy": 0, "context": 0, "length": 0, "composition": 0}}`,
+            wantErr: "must not all be zero",
+        },
+        {
+            name:    "negative weight",
+            data:    `{"weights": {"specificity": 0.5, "entropy": -0.1, "context": 0.2, "length": 0.2, "composition": 0.2}}`,
+            wantErr: "negative weight for entropy",
+        },
+        {
+            name:    "missing weight",
+            data:    `{"weights": {"specificity": 0.5, "entropy": 0.2, "context": 0.2, "length": 0.1}}`,
+            wantErr: "missing weight for composition",
+        },
+        {
+            name:    "unknown factor",
+            data:    `{"weights": {"specificity": 0.3, "entropy": 0.25, "context": 0.2, "length": 0.15, "composition": 0.1, "color": 1}}`,
+            wantErr: `unknown factor "color"`,
+        },
+        {name: "not JSON", data: `weights: {}`, wantErr: "parsing calibration"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            path := filepath.Join(t.TempDir(), "model.json")
+            if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+                t.Fatal(err)
+            }
+
+            calibration, err := LoadCalibration(path)
+            if tt.wantErr == "" {
+                if err != nil {
+                    t.Fatalf("unexpected error: %v", err)
+                }
+                // Accepted weights always give a defined score
+                calc := NewConfidenceCalculator()
+                calc.calibration = calibration
+                if confidence := calc.CalculateConfidence("Xk29dLq8zQ2w", "password: ", SecretPattern{Specificity: 0.5}, MatchTraits{}); math.IsNaN(confidence) {
+                    t.Error("confidence is NaN")
+                }
+                return
+            }
+            if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                t.Errorf("error = %v, want %q", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestSaveCalibrationRoundTrip(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "model.json")
+    saved := &Calibration{Samples: 12, Weights: DefaultFactorWeights(), Channels: map[string]float64{"c1": 0.4}}
+    if err := SaveCalibration(path, saved); err != nil {
+        t.Fatal(err)
+    }
+
+    loaded, err := LoadCalibration(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if loaded.Samples != 12 || loaded.Weight("entropy") != 0.25 || loaded.Channels["c1"] != 0.4 {
+        t.Errorf("loaded %+v", loaded)
+    }
+}
+
+func TestFeedbackShift(t *testing.T) {
+    calibration := &Calibration{
+        Rules:    map[string]float64{"generic-api-key": -1.5},
+        Channels: map[string]float64{"c1": -1},
+        Tokens:   map[string]float64{"sandbox": -0.5, "prod": 0.5},
+    }
+    tests := []struct {
+        name    string
+        ruleID  string
+        channel string
+        tokens  []string
+        want    float64
+    }{
+        {name: "no calibration entries", ruleID: "aws-access-key", channel: "c2", want: 0},
+        {name: "rule and token", ruleID: "generic-api-key", channel: "c2", tokens: []string{"prod"}, want: -1},
+        {name: "capped", ruleID: "generic-api-key", channel: "c1", tokens: []string{"sandbox"}, want: -maxFeedbackShift},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := calibration.FeedbackShift(tt.ruleID, tt.channel, tt.tokens); got != tt.want {
+                t.Errorf("FeedbackShift = %v, want %v", got, tt.want)
+            }
+        })
+    }
+
+    var none *Calibration
+    if got := none.FeedbackShift("generic-api-key", "c1", nil); got != 0 {
+        t.Errorf("nil calibration shift = %v", got)
+    }
+}
@@ -1,84 +1,186 @@
 package detector
 
 import (
+	"encoding/base64"
+	"fmt"
 	"math"
 	"strings"
+
+	"stackguard-task/internal/models"
 )
 
 type ConfidenceCalculator struct {
-    entropyThreshold float64
+    calibration *Calibration // learned from analyst feedback, nil for the default weights
+}
+
+// MatchTraits describes where in the message markup a match was found
+type MatchTraits struct {
+    InCodeBlock  bool              // inside <code>/<pre>, pasted secrets there are usually real
+    InLinkHref   bool              // inside a hyperlink target rather than visible text
+    Deobfuscated bool              // only matched after removing zero-width/look-alike characters or spacing
+    Validation   *ValidationResult // offline structural check, nil when the rule has none
+    // ParentContext is the text of the message being replied to, empty for thread roots
+    ParentContext string
+    ChannelID     string          // channel the message was posted in, for learned per-channel feedback
+    Profile       *ScoringProfile // scoring profile for the rule and channel, nil for the default
+    Keywords      *KeywordSet     // keywords for the message's languages, nil for English only
 }
 
 func NewConfidenceCalculator() *ConfidenceCalculator {
-    return &ConfidenceCalculator{
-        entropyThreshold: 3.5, // Minimum entropy for high confidence
-    }
+    return &ConfidenceCalculator{}
 }
 
 // Computes secret score 0.0 - 1.0
-func (cc *ConfidenceCalculator) CalculateConfidence(secret, context, secretType string) float64 {
-    var factors []float64
-    
+func (cc *ConfidenceCalculator) CalculateConfidence(secret, context string, pattern SecretPattern, traits MatchTraits) float64 {
+    return cc.ExplainConfidence(secret, context, pattern, traits).Confidence
+}
+
+// ExplainConfidence computes the score of CalculateConfidence together with
+// every factor and every bonus or penalty that went into it
+func (cc *ConfidenceCalculator) ExplainConfidence(secret, context string, pattern SecretPattern, traits MatchTraits) models.ConfidenceExplanation {
     // Factor 1: Pattern specificity (0.0 - 1.0)
-    patternSpecificity := cc.calculatePatternSpecificity(secret, secretType)
-    factors = append(factors, patternSpecificity)
+    patternSpecificity := cc.calculatePatternSpecificity(pattern)
     
     // Factor 2: Entropy analysis (0.0 - 1.0)
     entropyScore := cc.calculateEntropyScore(secret)
-    factors = append(factors, entropyScore)
     
     // Factor 3: Context analysis (0.0 - 1.0)
-    contextScore := cc.calculateContextScore(context)
-    factors = append(factors, contextScore)
+    contextTrace := &scoreTrace{factor: "context"}
+    contextScore := cc.calculateContextScore(context, pattern.Keywords, traits.Keywords, contextTrace)
+    contextScore = cc.applyParentContext(contextScore, traits.ParentContext, pattern.Keywords, traits.Keywords, contextTrace)
+    contextScore = cc.applyMarkupTraits(contextScore, pattern, traits, contextTrace)
     
     // Factor 4: Length appropriateness (0.0 - 1.0)
-    lengthScore := cc.calculateLengthScore(secret, secretType)
-    factors = append(factors, lengthScore)
+    lengthScore := cc.calculateLengthScore(secret, pattern)
     
     // Factor 5: Character composition (0.0 - 1.0)
-    compositionScore := cc.calculateCompositionScore(secret, secretType)
-    factors = append(factors, compositionScore)
+    compositionScore := cc.calculateCompositionScore(secret, pattern.ID)
     
-    // Weighted average with emphasis on pattern specificity and entropy
-    weights := []float64{0.3, 0.25, 0.2, 0.15, 0.1}
+    // Weighted average with emphasis on pattern specificity and entropy,
+    // unless analyst feedback or the scoring profile has changed the weights
+    weight := func(name string) float64 {
+        if weight, ok := traits.Profile.weight(name); ok {
+            return weight
+        }
+        return cc.calibration.Weight(name)
+    }
+    factors := []models.ConfidenceFactor{
+        {Name: "specificity", Score: patternSpecificity, Weight: weight("specificity"), Detail: pattern.ID},
+        {Name: "entropy", Score: entropyScore, Weight: weight("entropy"), Detail: fmt.Sprintf("%.2f bits/char", cc.calculateShannonEntropy(secret))},
+        {Name: "context", Score: contextScore, Weight: weight("context")},
+        {Name: "length", Score: lengthScore, Weight: weight("length"), Detail: fmt.Sprintf("%d chars", len(secret))},
+        {Name: "composition", Score: compositionScore, Weight: weight("composition")},
+    }
     
     var weightedSum, totalWeight float64
-    for i, factor := range factors {
-        if i < len(weights) {
-            weightedSum += factor * weights[i]
-            totalWeight += weights[i]
-        }
+    for _, factor := range factors {
+        weightedSum += factor.Score * factor.Weight
+        totalWeight += factor.Weight
     }
     
-    confidence := weightedSum / totalWeight
+    baseScore := weightedSum / totalWeight
+    confidenceTrace := &scoreTrace{factor: "confidence"}
+    
+    // How often analysts confirmed or dismissed similar detections
+    confidence := cc.applyFeedback(baseScore, pattern.ID, traits.ChannelID, context, confidenceTrace)
     
     // Apply penalties for common false positive indicators
-    confidence = cc.applyFalsePositivePenalties(secret, context, confidence)
+    confidence = cc.applyFalsePositivePenalties(secret, context, confidence, traits.Keywords, confidenceTrace)
+    
+    // Values less random than the profile demands are likely placeholders
+    confidence = cc.applyEntropyThreshold(secret, confidence, traits.Profile, confidenceTrace)
+    
+    // Structural validation is the strongest signal available offline
+    confidence = cc.applyValidation(confidence, traits.Validation, confidenceTrace)
     
     // Ensure bounds
     confidence = math.Min(confidence, 1.0)
     confidence = math.Max(confidence, 0.0)
     
-    return confidence
+    return models.ConfidenceExplanation{
+        Factors:     factors,
+        BaseScore:   baseScore,
+        Adjustments: append(contextTrace.adjustments, confidenceTrace.adjustments...),
+        Confidence:  confidence,
+        Languages:   traits.Keywords.Languages(),
+    }
 }
 
-// calculatePatternSpecificity evaluates how specific the pattern match is
-func (cc *ConfidenceCalculator) calculatePatternSpecificity(secret, secretType string) float64 {
-    specificityScores := map[string]float64{
-        "Private Key":        0.99, // Very distinctive format
-        "GitHub Token":       0.98, // Highly specific prefix
-        "AWS Access Key":     0.95, // Very specific format
-        "Slack Token":        0.92, // Specific prefix patterns
-        "Google API Key":     0.90, // Specific prefix pattern
-        "Database URL":       0.85, // Protocol-specific
-        "JWT Token":          0.75, // Common format, but can vary
-        "AWS Secret Key":     0.60, // Base64-like, less specific
-        "API Key Generic":    0.50, // Generic pattern, less specific
-    }
-    
-    if score, exists := specificityScores[secretType]; exists {
+// applyEntropyThreshold halves the score of values below the profile's entropy threshold
+func (cc *ConfidenceCalculator) applyEntropyThreshold(secret string, confidence float64, profile *ScoringProfile, trace *scoreTrace) float64 {
+    if profile == nil || profile.EntropyThreshold == 0 {
+        return confidence
+    }
+    entropy := cc.calculateShannonEntropy(secret)
+    if entropy >= profile.EntropyThreshold {
+        return confidence
+    }
+    trace.note("below the profile's entropy threshold", fmt.Sprintf("%s: %.2f < %.2f bits/char", profile.Name, entropy, profile.EntropyThreshold), confidence, confidence*0.5)
+    return confidence * 0.5
+}
+
+// Adjustment reasons that feedback training needs to find in explanations
+const (
+    FeedbackReason         = "analyst feedback"
+    PassedValidationReason = "passed validation"
+)
+
+// Shifts the score by the learned feedback for the rule, channel and context words
+func (cc *ConfidenceCalculator) applyFeedback(score float64, ruleID, channelID, context string, trace *scoreTrace) float64 {
+    if cc.calibration == nil {
         return score
     }
+    tokens := ContextTokens(context)
+    shift := cc.calibration.FeedbackShift(ruleID, channelID, tokens)
+    adjusted := ApplyShift(score, shift)
+
+    // Name what moved the score most, for the explanation
+    var triggers []string
+    if shift := cc.calibration.Rules[ruleID]; shift != 0 {
+        triggers = append(triggers, fmt.Sprintf("rule %s %+.2f", ruleID, shift))
+    }
+    if shift := cc.calibration.Channels[channelID]; shift != 0 {
+        triggers = append(triggers, fmt.Sprintf("channel %s %+.2f", channelID, shift))
+    }
+    for _, token := range tokens {
+        if shift := cc.calibration.Tokens[token]; shift != 0 {
+            triggers = append(triggers, fmt.Sprintf("%q %+.2f", token, shift))
+        }
+    }
+    trace.note(FeedbackReason, strings.Join(triggers, ", "), score, adjusted)
+    return adjusted
+}
+
+// scoreTrace records the bonuses and penalties applied to one factor (or to
+// the weighted score) for the explanation. A nil trace records nothing.
+type scoreTrace struct {
+    factor      string
+    adjustments []models.ConfidenceAdjustment
+}
+
+func (st *scoreTrace) note(reason, trigger string, before, after float64) {
+    if st == nil || before == after {
+        return
+    }
+    st.adjustments = append(st.adjustments, models.ConfidenceAdjustment{
+        Factor:  st.factor,
+        Reason:  reason,
+        Trigger: trigger,
+        Before:  roundScore(before),
+        After:   roundScore(after),
+    })
+}
+
+// roundScore drops float noise (0.8999999) from explained scores
+func roundScore(score float64) float64 {
+    return math.Round(score*1e4) / 1e4
+}
+
+// calculatePatternSpecificity evaluates how specific the pattern match is
+func (cc *ConfidenceCalculator) calculatePatternSpecificity(pattern SecretPattern) float64 {
+    if pattern.Specificity > 0 {
+        return pattern.Specificity
+    }
     
     // Default moderate specificity
     return 0.5
@@ -123,8 +225,9 @@ func (cc *ConfidenceCalculator) calculateShannonEntropy(s string) float64 {
     return entropy
 }
 
-// Analyzes the surrounding context for indicators of secret / false positives
-func (cc *ConfidenceCalculator) calculateContextScore(context string) float64 {
+// Analyzes the surrounding context for indicators of secret / false positives,
+// in English and the languages keywords were detected for
+func (cc *ConfidenceCalculator) calculateContextScore(context string, ruleKeywords []string, keywords *KeywordSet, trace *scoreTrace) float64 {
     if context == "" {
         return 0.5 // Neutral if no context
     }
@@ -144,51 +247,86 @@ func (cc *ConfidenceCalculator) calculateContextScore(context string) float64 {
         "tutorial", "guide", "comment", "todo", "fixme",
     }
     
-    var positiveScore, negativeScore float64
+    contextScore := 0.5
     
     for _, keyword := range positiveKeywords {
-        if strings.Contains(lowerContext, keyword) {
-            positiveScore += 0.2
+        if found, ok := keywords.find(lowerContext, keyword); ok {
+            trace.note("credential keyword", found, contextScore, contextScore+0.2)
+            contextScore += 0.2
+        }
+    }
+    
+    // Rule-specific keywords from the rule pack
+    for _, keyword := range ruleKeywords {
+        if found, ok := keywords.find(lowerContext, keyword); ok {
+            trace.note("rule keyword", found, contextScore, contextScore+0.2)
+            contextScore += 0.2
         }
     }
     
+    // Whole words only, "latest" or "attestation" say nothing about a test
     for _, keyword := range negativeKeywords {
-        if strings.Contains(lowerContext, keyword) {
-            negativeScore += 0.3
+        if found, ok := keywords.findWord(lowerContext, keyword); ok {
+            trace.note("test/example keyword", found, contextScore, contextScore-0.3)
+            contextScore -= 0.3
         }
     }
     
     // Calculate final context score
-    contextScore := 0.5 + positiveScore - negativeScore
+    clamped := math.Max(0.0, math.Min(contextScore, 1.0))
+    trace.note("clamped to 0.0 - 1.0", "", contextScore, clamped)
     
-    if contextScore < 0.0 {
-        contextScore = 0.0
+    return clamped
+}
+
+// Blends in the message being replied to: "here's the prod DB password" in the
+// parent makes a bare value in the reply more likely a secret. The parent is
+// further from the match than its own context, so it counts half.
+func (cc *ConfidenceCalculator) applyParentContext(contextScore float64, parent string, ruleKeywords []string, keywords *KeywordSet, trace *scoreTrace) float64 {
+    if parent == "" {
+        return contextScore
+    }
+    parentTrace := &scoreTrace{factor: "parent context"}
+    parentScore := cc.calculateContextScore(parent, ruleKeywords, keywords, parentTrace)
+    adjusted := math.Max(0.0, math.Min(contextScore+(parentScore-0.5)*0.5, 1.0))
+    if trace != nil {
+        trace.adjustments = append(trace.adjustments, parentTrace.adjustments...)
     }
-    if contextScore > 1.0 {
-        contextScore = 1.0
+    trace.note("replied-to message, at half weight", "", contextScore, adjusted)
+    return adjusted
+}
+
+// Adjusts the context score based on the markup around the match and how it was found
+func (cc *ConfidenceCalculator) applyMarkupTraits(contextScore float64, pattern SecretPattern, traits MatchTraits, trace *scoreTrace) float64 {
+    if traits.InCodeBlock {
+        trace.note("inside a code block", "", contextScore, contextScore+0.25)
+        contextScore += 0.25
     }
     
-    return contextScore
+    // Nobody hides a placeholder, deliberate evasion points to a real secret
+    if traits.Deobfuscated {
+        trace.note("deobfuscated", "", contextScore, contextScore+0.2)
+        contextScore += 0.2
+    }
+    
+    // Link targets are full of long IDs and path segments that trip loose
+    // patterns, distinctive formats are trusted regardless
+    if traits.InLinkHref && pattern.Specificity < 0.7 {
+        trace.note("inside a link target", "", contextScore, contextScore-0.15)
+        contextScore -= 0.15
+    }
+    
+    clamped := math.Max(0.0, math.Min(contextScore, 1.0))
+    trace.note("clamped to 0.0 - 1.0", "", contextScore, clamped)
+    return clamped
 }
 
-func (cc *ConfidenceCalculator) calculateLengthScore(secret, secretType string) float64 {
+func (cc *ConfidenceCalculator) calculateLengthScore(secret string, pattern SecretPattern) float64 {
     length := len(secret)
     
-    // Expected length ranges for different secret types
-    lengthRanges := map[string][2]int{
-        "AWS Access Key":     {20, 20},   // Exactly 20 chars
-        "AWS Secret Key":     {40, 40},   // Exactly 40 chars
-        "GitHub Token":       {40, 40},   // ghp_ + 36 chars
-        "Google API Key":     {39, 39},   // AIza + 35 chars
-        "JWT Token":          {50, 500},  // Variable, but typically long
-        "API Key Generic":    {16, 64},   // Common range
-        "Slack Token":        {24, 56},   // Various formats
-        "Database URL":       {20, 200},  // Variable length
-        "Private Key":        {100, 5000}, // Very variable
-    }
-    
-    if expectedRange, exists := lengthRanges[secretType]; exists {
-        minLen, maxLen := expectedRange[0], expectedRange[1]
+    // Expected length range comes from the rule pack
+    if pattern.MaxLength > 0 {
+        minLen, maxLen := pattern.MinLength, pattern.MaxLength
         
         if length >= minLen && length <= maxLen {
             return 1.0 // Perfect length
@@ -203,11 +341,12 @@ func (cc *ConfidenceCalculator) calculateLengthScore(secret, secretType string)
         }
     }
     
-    return 0.7 // Default for unknown types
+    return 0.7 // Default for rules without a length range
 }
 
-// Analyzes character composition
-func (cc *ConfidenceCalculator) calculateCompositionScore(secret, secretType string) float64 {
+// Analyzes character composition; type-specific checks are keyed on the rule
+// ID, which stays fixed when a pack renames a rule
+func (cc *ConfidenceCalculator) calculateCompositionScore(secret, ruleID string) float64 {
     var score float64 = 0.5 // Base score
     
     var hasUpper, hasLower, hasDigit, hasSpecial bool
@@ -240,22 +379,57 @@ func (cc *ConfidenceCalculator) calculateCompositionScore(secret, secretType str
     score += float64(diversity) * 0.1
     
     // Type-specific composition analysis
-    switch secretType {
-    case "AWS Access Key":
+    switch ruleID {
+    case "aws-access-key", "aws-temporary-access-key":
         // Should be all uppercase alphanumeric
         if hasUpper && hasDigit && !hasLower && !hasSpecial {
             score += 0.3
         }
-    case "JWT Token":
+    case "jwt":
         // Should have base64-like composition
         if hasUpper && hasLower && hasDigit && specialCount <= 2 {
             score += 0.2
         }
-    case "Private Key":
+    case "private-key":
         // Should have specific format indicators
         if hasUpper && hasLower && hasSpecial {
             score += 0.3
         }
+    case "aws-session-token":
+        // Pure base64, long enough to hold the serialized session
+        if hasUpper && hasLower && hasDigit && len(secret) >= 200 {
+            score += 0.2
+        }
+    case "azure-ad-client-secret":
+        // Current client secrets carry a "Q~" marker after a 3 char prefix and a digit
+        if strings.Index(secret, "Q~") == 4 && hasUpper && hasLower && hasDigit {
+            score += 0.3
+        }
+    case "azure-storage-account-key", "azure-storage-connection-string":
+        // Account keys are exactly 64 random bytes
+        if decodesToAzureAccountKey(secret) {
+            score += 0.3
+        }
+    case "azure-sas-token":
+        // A usable SAS carries an expiry and permissions next to the signature
+        if strings.Contains(secret, "se=") && strings.Contains(secret, "sp=") {
+            score += 0.2
+        }
+    case "azure-devops-pat", "azure-devops-pat-legacy":
+        // Legacy PATs are lowercase base32, current ones embed the AZDO signature
+        if strings.Contains(secret, "AZDO") || (!hasUpper && !hasSpecial) {
+            score += 0.3
+        }
+    case "password-assignment", "password-cli-flag", "credential-pair":
+        // Passwords are short and low entropy; mixing classes is what sets them apart from prose
+        if diversity >= 3 {
+            score += 0.2
+        }
+    case "gcp-service-account-key":
+        // Real key files name the service account they belong to
+        if strings.Contains(secret, "gserviceaccount.com") || strings.Contains(secret, "private_key_id") {
+            score += 0.3
+        }
     }
     
     // Penalize if too uniform (like all same character)
@@ -278,9 +452,23 @@ func (cc *ConfidenceCalculator) calculateCompositionScore(secret, secretType str
     return score
 }
 
+// decodesToAzureAccountKey reports whether the AccountKey (or the secret itself)
+// is base64 for exactly 64 bytes
+func decodesToAzureAccountKey(secret string) bool {
+    key := secret
+    if i := strings.Index(secret, "AccountKey="); i != -1 {
+        key = secret[i+len("AccountKey="):]
+        if end := strings.IndexByte(key, ';'); end != -1 {
+            key = key[:end]
+        }
+    }
+    decoded, err := base64.StdEncoding.DecodeString(key)
+    return err == nil && len(decoded) == 64
+}
+
 // Applies penalties for common false positive patterns
-func (cc *ConfidenceCalculator) applyFalsePositivePenalties(secret, context string, confidence float64) float64 {
-    lowerSecret := strings.ToLower(secret)
+func (cc *ConfidenceCalculator) applyFalsePositivePenalties(secret, context string, confidence float64, keywords *KeywordSet, trace *scoreTrace) float64 {
+    lowerSecret := strings.ToLower(credentialPart(secret))
     lowerContext := strings.ToLower(context)
     
     // Common false positive patterns
@@ -293,11 +481,13 @@ func (cc *ConfidenceCalculator) applyFalsePositivePenalties(secret, context stri
     }
     
     for _, pattern := range falsePositivePatterns {
-        if strings.Contains(lowerSecret, pattern) {
+        if found, ok := keywords.find(lowerSecret, pattern); ok {
+            trace.note("false-positive pattern in the secret", found, confidence, confidence*0.1)
             confidence *= 0.1 // Severe penalty if pattern is in the secret itself
             break
         }
-        if strings.Contains(lowerContext, pattern) {
+        if found, ok := keywords.findWord(lowerContext, pattern); ok {
+            trace.note("false-positive pattern in the context", found, confidence, confidence*0.6)
             confidence *= 0.6 // Mild penalty if pattern only appears in nearby context
             // Accumulates mild penalties for multiple patterns by not breaking here
         }
@@ -305,17 +495,38 @@ func (cc *ConfidenceCalculator) applyFalsePositivePenalties(secret, context stri
     
     // Repetitive patterns penalty
     if cc.isRepetitive(secret) {
+        trace.note("repetitive value", "", confidence, confidence*0.3)
         confidence *= 0.3
     }
     
     // All same character penalty
     if cc.isAllSameCharacter(secret) {
+        trace.note("single repeated character", "", confidence, confidence*0.1)
         confidence *= 0.1
     }
     
     return confidence
 }
 
+// Boosts structurally valid matches and penalizes ones that failed their check
+func (cc *ConfidenceCalculator) applyValidation(confidence float64, validation *ValidationResult, trace *scoreTrace) float64 {
+    if validation == nil || validation.Inconclusive {
+        return confidence
+    }
+    if validation.Veto {
+        trace.note("rejected by validator", validation.Reason, confidence, 0.0)
+        return 0.0
+    }
+    if validation.Valid {
+        // Close half of the remaining gap to 1.0
+        adjusted := confidence + (1.0-confidence)*0.5
+        trace.note(PassedValidationReason, validation.Reason, confidence, adjusted)
+        return adjusted
+    }
+    trace.note("failed validation", validation.Reason, confidence, confidence*0.7)
+    return confidence * 0.7
+}
+
 func (cc *ConfidenceCalculator) isRepetitive(s string) bool {
     if len(s) < 4 {
         return false
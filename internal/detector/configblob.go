@@ -0,0 +1,587 @@
+package detector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"stackguard-task/internal/models"
+)
+
+// configFormat recognizes one kind of pasted credential file
+type configFormat struct {
+    ruleID     string
+    name       string  // secret type reported on the grouped detection
+    confidence float64 // confidence for a blob holding a single credential
+    find       func(content string) []configBlob
+}
+
+// configBlob is a file-like region of the scanned text and the credentials found in it
+type configBlob struct {
+    start       int
+    end         int
+    credentials []configCredential
+}
+
+// configCredential is one sensitive setting of a config blob
+type configCredential struct {
+    key   string
+    value string
+    start int // offset of the value in the scanned text
+}
+
+func (cb configBlob) keys() []string {
+    keys := make([]string, len(cb.credentials))
+    for i, credential := range cb.credentials {
+        keys[i] = credential.key
+    }
+    return keys
+}
+
+// A config file format says less about a value than a token prefix does, so
+// the context of a pasted file can suppress it like a generic match
+const configBlobSpecificity = 0.5
+
+// pattern describes the format to suppression, masking and the audit trail
+func (cf configFormat) pattern() SecretPattern {
+    return SecretPattern{ID: cf.ruleID, Name: cf.name, Specificity: configBlobSpecificity, MaskFull: true}
+}
+
+// Formats are tried in order; a region claimed by an earlier format is not
+// reported again (an .npmrc would otherwise also parse as a .env file)
+var configFormats = []configFormat{
+    {ruleID: "config-docker", name: "Docker config.json", confidence: 0.90, find: findDockerConfigs},
+    {ruleID: "config-kubeconfig", name: "Kubeconfig", confidence: 0.90, find: findKubeconfigs},
+    {ruleID: "config-netrc", name: ".netrc", confidence: 0.85, find: findNetrcs},
+    {ruleID: "config-npmrc", name: ".npmrc", confidence: 0.90, find: findNpmrcs},
+    {ruleID: "config-dotenv", name: ".env File", confidence: 0.80, find: findDotenvs},
+}
+
+// scanConfigBlobs reports pasted config files as one detection each. Regex
+// detections inside a blob are folded into it so a pasted file does not turn
+// into a scatter of unrelated findings.
+func (p *scanPass) scanConfigBlobs(content string) {
+    var claimed [][2]int
+    overlapsClaimed := func(start, end int) bool {
+        for _, span := range claimed {
+            if start < span[1] && span[0] < end {
+                return true
+            }
+        }
+        return false
+    }
+
+    for _, format := range configFormats {
+        for _, blob := range format.find(content) {
+            if len(blob.credentials) == 0 || overlapsClaimed(blob.start, blob.end) {
+                continue
+            }
+            claimed = append(claimed, [2]int{blob.start, blob.end})
+            p.addConfigDetection(content, format, blob)
+        }
+    }
+}
+
+func (p *scanPass) addConfigDetection(content string, format configFormat, blob configBlob) {
+    msg := p.msg
+    startOffset, endOffset := p.normalized.originalSpan(blob.start, blob.end)
+
+    blob.credentials = p.unsuppressedCredentials(content, format, blob)
+    if len(blob.credentials) == 0 {
+        return
+    }
+    keys := blob.keys()
+
+    // Each extra credential in the same file makes it less likely to be a sample
+    confidence := 1 - (1-format.confidence)*math.Pow(0.5, float64(len(keys)-1))
+    trace := &scoreTrace{factor: "confidence"}
+    trace.note("more credentials in the same file", strings.Join(keys, ", "), format.confidence, confidence)
+
+    // The regex hits inside the blob are absorbed, keeping the strongest score
+    inside := func(detection models.SecretDetection) bool {
+        return detection.StartOffset >= startOffset && detection.EndOffset <= endOffset
+    }
+    for _, detection := range p.detections {
+        if inside(detection) && detection.Confidence > confidence {
+            trace.note("stronger match inside the file", detection.SecretType, confidence, detection.Confidence)
+            confidence = detection.Confidence
+        }
+    }
+    confidence = math.Min(confidence, 1.0)
+
+    secret := content[blob.start:blob.end]
+    masked := maskConfigBlob(keys)
+    context := extractContext(content, blob.start, blob.end, masked)
+    profile := p.profiles.Resolve(format.ruleID, msg.ChannelID, msg.TeamID)
+    if confidence < profile.MinConfidence {
+        p.suppress(format.pattern(), secret, context, startOffset, SuppressedByConfidence,
+            fmt.Sprintf("%s profile minimum %.2f", profile.Name, profile.MinConfidence), confidence)
+        return
+    }
+
+    var kept []models.SecretDetection
+    for _, detection := range p.detections {
+        if !inside(detection) {
+            kept = append(kept, detection)
+        }
+    }
+    p.detections = kept
+
+    severity := "HIGH"
+    if len(keys) > 1 {
+        // Several credentials together usually means a whole environment leaked
+        severity = "CRITICAL"
+    }
+
+    line, column := p.normalized.lineColumn(startOffset)
+    traits := p.normalized.traitsAt(startOffset, endOffset)
+
+    p.detections = append(p.detections, models.SecretDetection{
+        ID:            generateDetectionID(msg.ID, secret, startOffset),
+        MessageID:     msg.ID,
+        ChannelID:     msg.ChannelID,
+        TeamID:        msg.TeamID,
+        ThreadID:      msg.ThreadID,
+        ReplyToID:     msg.ReplyToID,
+        UserID:        msg.From.User.ID,
+        UserName:      msg.From.User.DisplayName,
+        SecretType:    format.name,
+        RuleID:        format.ruleID,
+        MaskedValue:   masked,
+        FullValue:     secret,
+        Confidence:    confidence,
+        Context:       context,
+        DetectedAt:    time.Now(),
+        Severity:      severity,
+        Status:        "new",
+        StartOffset:   startOffset,
+        EndOffset:     endOffset,
+        Line:          line,
+        Column:        column,
+        InCodeBlock:   traits.InCodeBlock,
+        Deobfuscated:  traits.Deobfuscated,
+        SensitiveKeys: keys,
+        Profile:       profile.Name,
+        Explanation: &models.ConfidenceExplanation{
+            Factors:     []models.ConfidenceFactor{{Name: "config file", Score: format.confidence, Weight: 1, Detail: format.name}},
+            BaseScore:   format.confidence,
+            Adjustments: trace.adjustments,
+            Confidence:  confidence,
+        },
+    })
+}
+
+// unsuppressedCredentials drops the blob's values that a rule allowlists or
+// the suppression vocabulary marks as test or placeholder values, recording
+// each. A value is judged by its own line and the text around the file.
+func (p *scanPass) unsuppressedCredentials(content string, format configFormat, blob configBlob) []configCredential {
+    pattern := format.pattern()
+    around := extractContext(content, blob.start, blob.end, "\n")
+
+    var kept []configCredential
+    for _, credential := range blob.credentials {
+        lineStart := strings.LastIndexByte(content[:credential.start], '\n') + 1
+        lineEnd := len(content)
+        if i := strings.IndexByte(content[credential.start:], '\n'); i != -1 {
+            lineEnd = credential.start + i
+        }
+        valueEnd := min(credential.start+len(credential.value), lineEnd)
+        context := extractContext(content, lineStart, lineEnd, content[lineStart:credential.start]+pattern.mask(credential.value)+content[valueEnd:lineEnd]) + "\n" + around
+        startOffset, _ := p.normalized.originalSpan(credential.start, valueEnd)
+
+        rule, trigger, suppressed := p.allowlisted(credential.value)
+        if !suppressed {
+            rule, trigger, suppressed = p.suppression.suppress(credential.value, context, pattern, p.keywords)
+        }
+        if suppressed {
+            p.suppress(pattern, credential.value, context, startOffset, rule, credential.key+": "+trigger, 0)
+            continue
+        }
+        kept = append(kept, credential)
+    }
+    return kept
+}
+
+// allowlisted reports whether any rule allowlists a value, e.g. a published emulator key
+func (p *scanPass) allowlisted(value string) (string, string, bool) {
+    for _, rule := range p.rules.patterns {
+        for _, allow := range rule.Allowlist {
+            if allow.MatchString(value) {
+                return SuppressedByAllowlist, allow.String(), true
+            }
+        }
+    }
+    return "", "", false
+}
+
+// maskConfigBlob shows which keys were present without any of their values
+func maskConfigBlob(keys []string) string {
+    lines := make([]string, len(keys))
+    for i, key := range keys {
+        lines[i] = key + ": " + maskFullSecret("")
+    }
+    return strings.Join(lines, "\n")
+}
+
+// Values a sample config file ships instead of a credential
+var configPlaceholderPattern = regexp.MustCompile(`(?i)^(?:<[^>]*>|change[-_]?me|your[-_].*|.*[-_]here|.*placeholder.*)$`)
+
+// Booleans and plain numbers are settings, not credentials
+var configSettingPattern = regexp.MustCompile(`(?i)^(?:true|false|yes|no|on|off|enabled|disabled|-?[0-9]+)$`)
+
+// isCredentialValue reports whether a config value holds an actual secret
+// rather than nothing, a setting, a plain URL, a variable reference or a
+// placeholder
+func isCredentialValue(value string) bool {
+    value = unquoteConfigValue(value)
+    if value == "" || configPlaceholderPattern.MatchString(value) || configSettingPattern.MatchString(value) {
+        return false
+    }
+    // A URL only carries a credential in its user info
+    if strings.Contains(value, "://") && credentialPart(value) == value {
+        return false
+    }
+    return !validatePassword(value).Veto
+}
+
+func unquoteConfigValue(value string) string {
+    return strings.Trim(strings.TrimSpace(value), `"'`)
+}
+
+// lineSpan is a [start, end) line of the scanned text, without its line break
+type lineSpan struct {
+    start int
+    end   int
+}
+
+func splitLines(content string) []lineSpan {
+    var lines []lineSpan
+    start := 0
+    for i := 0; i <= len(content); i++ {
+        if i == len(content) || content[i] == '\n' {
+            lines = append(lines, lineSpan{start: start, end: i})
+            start = i + 1
+        }
+    }
+    return lines
+}
+
+// findLineBlobs groups consecutive lines accepted by parse into blobs. parse
+// returns ok for lines belonging to the format and the sensitive key on that
+// line, if any, with its value, which ends the line. Blank lines and #
+// comments do not end a run.
+func findLineBlobs(content string, minLines int, parse func(line string) (key, value string, ok bool)) []configBlob {
+    var blobs []configBlob
+    var current *configBlob
+    lineCount := 0
+
+    flush := func() {
+        if current != nil && lineCount >= minLines {
+            blobs = append(blobs, *current)
+        }
+        current, lineCount = nil, 0
+    }
+
+    for _, span := range splitLines(content) {
+        raw := content[span.start:span.end]
+        line := strings.TrimSpace(raw)
+        if line == "" || strings.HasPrefix(line, "#") {
+            continue
+        }
+        key, value, ok := parse(line)
+        if !ok {
+            flush()
+            continue
+        }
+        if current == nil {
+            current = &configBlob{start: span.start}
+        }
+        current.end = span.end
+        lineCount++
+        if key != "" {
+            // The value ends the trimmed line, quotes are not part of it
+            valueStart := span.start + len(strings.TrimRight(raw, " \t\r")) - len(value)
+            unquoted := unquoteConfigValue(value)
+            valueStart += strings.Index(value, unquoted)
+            current.credentials = append(current.credentials, configCredential{key: key, value: unquoted, start: valueStart})
+        }
+    }
+    flush()
+
+    return blobs
+}
+
+var dotenvLinePattern = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_.]*)\s*=\s*(.*)$`)
+
+// Variable names that hold credentials, as whole _-separated segments; a
+// name ending in a segment of nonCredentialEnvSuffixes configures the
+// credential rather than holding it (AUTH_ENABLED, TOKEN_TTL_SECONDS)
+var (
+    sensitiveEnvNames = [][]string{
+        {"PASSWORD"}, {"PASSWD"}, {"PWD"}, {"SECRET"}, {"TOKEN"}, {"APIKEY"},
+        {"AUTH"}, {"CREDENTIAL"}, {"CREDENTIALS"}, {"DSN"},
+        {"API", "KEY"}, {"PRIVATE", "KEY"}, {"ACCESS", "KEY"},
+        {"CONNECTION", "STRING"}, {"DATABASE", "URL"},
+    }
+    nonCredentialEnvSuffixes = map[string]bool{
+        "TTL": true, "URL": true, "URI": true, "ENDPOINT": true, "HOST": true, "PORT": true,
+        "ENABLED": true, "DISABLED": true, "ENABLE": true, "DISABLE": true,
+        "TIMEOUT": true, "SECONDS": true, "EXPIRY": true, "EXPIRES": true,
+        "LENGTH": true, "SIZE": true, "COUNT": true, "PATH": true, "FILE": true, "DIR": true,
+        "TYPE": true, "MODE": true, "LEVEL": true, "HEADER": true, "ID": true, "NAME": true,
+        "USER": true, "REGION": true, "ISSUER": true, "AUDIENCE": true, "SCOPE": true,
+    }
+)
+
+// isSensitiveEnvName reports whether a variable holds a credential
+func isSensitiveEnvName(name string) bool {
+    segments := strings.FieldsFunc(strings.ToUpper(name), func(r rune) bool { return r == '_' || r == '.' })
+    for _, sensitive := range sensitiveEnvNames {
+        for i := 0; i+len(sensitive) <= len(segments); i++ {
+            if !slices.Equal(segments[i:i+len(sensitive)], sensitive) {
+                continue
+            }
+            // DATABASE_URL holds the credential, DATABASE_PASSWORD_FILE does not
+            if i+len(sensitive) == len(segments) || !nonCredentialEnvSuffixes[segments[len(segments)-1]] {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// findDotenvs finds runs of at least three KEY=value lines
+func findDotenvs(content string) []configBlob {
+    return findLineBlobs(content, 3, func(line string) (string, string, bool) {
+        m := dotenvLinePattern.FindStringSubmatch(line)
+        if m == nil {
+            return "", "", false
+        }
+        if isSensitiveEnvName(m[1]) && isCredentialValue(m[2]) {
+            return m[1], m[2], true
+        }
+        return "", "", true
+    })
+}
+
+var npmrcLinePattern = regexp.MustCompile(`^((?://[^\s=]*:)?[@\w.-]+(?::[\w.-]+)?)\s*=\s*(.*)$`)
+
+// findNpmrcs finds .npmrc sections carrying registry auth. A lone auth line is
+// left to the token rules, it is not a pasted file.
+func findNpmrcs(content string) []configBlob {
+    blobs := findLineBlobs(content, 2, func(line string) (string, string, bool) {
+        m := npmrcLinePattern.FindStringSubmatch(line)
+        if m == nil {
+            return "", "", false
+        }
+        key := m[1]
+        setting := key[strings.LastIndex(key, ":")+1:]
+        switch setting {
+        case "_authToken", "_auth", "_password":
+            if isCredentialValue(m[2]) {
+                return key, m[2], true
+            }
+        }
+        // Only npm-style settings continue a run, so prose KEY=value lines do not
+        return "", "", strings.HasPrefix(key, "//") || strings.HasPrefix(key, "@") ||
+            setting == "registry" || setting == "always-auth" || setting == "email" || setting == "username"
+    })
+
+    // Plain settings alone are not a credential file
+    var withAuth []configBlob
+    for _, blob := range blobs {
+        if len(blob.credentials) > 0 {
+            withAuth = append(withAuth, blob)
+        }
+    }
+    return withAuth
+}
+
+var netrcEntryPattern = regexp.MustCompile(`\b(?:machine\s+(\S+)|default)((?:\s+(?:login|password|account|port)\s+\S+)+)`)
+var netrcPasswordPattern = regexp.MustCompile(`\bpassword\s+(\S+)`)
+
+// findNetrcs finds machine entries with a password; adjacent entries form one blob
+func findNetrcs(content string) []configBlob {
+    var blobs []configBlob
+    for _, loc := range netrcEntryPattern.FindAllStringSubmatchIndex(content, -1) {
+        password := netrcPasswordPattern.FindStringSubmatchIndex(content[loc[4]:loc[5]])
+        if password == nil {
+            continue
+        }
+        value := content[loc[4]+password[2] : loc[4]+password[3]]
+        if !isCredentialValue(value) {
+            continue
+        }
+
+        key := "default password"
+        if loc[2] >= 0 {
+            key = "machine " + content[loc[2]:loc[3]] + " password"
+        }
+
+        credential := configCredential{key: key, value: value, start: loc[4] + password[2]}
+        if n := len(blobs); n > 0 && strings.TrimSpace(content[blobs[n-1].end:loc[0]]) == "" {
+            blobs[n-1].end = loc[1]
+            blobs[n-1].credentials = append(blobs[n-1].credentials, credential)
+            continue
+        }
+        blobs = append(blobs, configBlob{start: loc[0], end: loc[1], credentials: []configCredential{credential}})
+    }
+    return blobs
+}
+
+// Keys in a kubeconfig whose values are credentials
+var kubeconfigSecretKeys = map[string]bool{
+    "client-key-data": true,
+    "token":           true,
+    "password":        true,
+    "id-token":        true,
+    "refresh-token":   true,
+    "client-secret":   true,
+    "access-token":    true,
+}
+
+var yamlLinePattern = regexp.MustCompile(`^(?:- )?([A-Za-z0-9_.-]+):(?:\s+(.*))?$|^- \S`)
+
+// findKubeconfigs finds YAML runs that look like a kubeconfig. Indentation is
+// already collapsed by normalization, so keys are read line by line rather
+// than by parsing the YAML tree.
+func findKubeconfigs(content string) []configBlob {
+    var blobs []configBlob
+    candidates := findLineBlobs(content, 4, func(line string) (string, string, bool) {
+        m := yamlLinePattern.FindStringSubmatch(line)
+        if m == nil {
+            return "", "", false
+        }
+        if kubeconfigSecretKeys[m[1]] && isCredentialValue(m[2]) {
+            return m[1], m[2], true
+        }
+        return "", "", true
+    })
+
+    for _, blob := range candidates {
+        text := content[blob.start:blob.end]
+        if strings.Contains(text, "kind: Config") || (strings.Contains(text, "clusters:") && strings.Contains(text, "users:")) {
+            blobs = append(blobs, blob)
+        }
+    }
+    return blobs
+}
+
+// dockerConfig is the part of ~/.docker/config.json that holds registry credentials
+type dockerConfig struct {
+    Auths map[string]map[string]interface{} `json:"auths"`
+}
+
+// Credential fields of a Docker config auths entry
+var dockerAuthFields = []string{"auth", "password", "identitytoken", "registrytoken"}
+
+// Larger objects are not a pasted config.json and are not decoded
+const maxDockerConfigSize = 64 << 10
+
+// findDockerConfigs finds JSON objects with an "auths" map. Braces are paired
+// in a single pass, so each "auths" key is decoded once, with the object that
+// directly encloses it.
+func findDockerConfigs(content string) []configBlob {
+    var blobs []configBlob
+    objects := enclosingJSONObjects(content, `"auths"`)
+    searchFrom := 0
+
+    for _, object := range objects {
+        open, end := object[0], object[1]
+        if open < searchFrom || end-open > maxDockerConfigSize {
+            continue
+        }
+        var config dockerConfig
+        if err := json.Unmarshal([]byte(content[open:end]), &config); err != nil || config.Auths == nil {
+            continue
+        }
+
+        blob := configBlob{start: open, end: end, credentials: dockerAuthCredentials(content[open:end], open, config)}
+        if len(blob.credentials) > 0 {
+            blobs = append(blobs, blob)
+        }
+        searchFrom = end
+    }
+    return blobs
+}
+
+// enclosingJSONObjects returns the [start, end) span of the innermost closed
+// {...} around each occurrence of key outside a JSON string, in order. Strings
+// do not span lines, so a stray quote in prose does not swallow the rest.
+func enclosingJSONObjects(content, key string) [][2]int {
+    var opens []int     // unclosed '{' offsets, innermost last
+    var enclosing []int // innermost '{' around each key
+    closes := make(map[int]int)
+    inString, escaped, stringStart := false, false, 0
+
+    for i := 0; i < len(content); i++ {
+        c := content[i]
+        if inString {
+            switch {
+            case escaped:
+                escaped = false
+            case c == '\\':
+                escaped = true
+            case c == '\n':
+                inString = false
+            case c == '"':
+                inString = false
+                if content[stringStart:i+1] == key && len(opens) > 0 {
+                    enclosing = append(enclosing, opens[len(opens)-1])
+                }
+            }
+            continue
+        }
+        switch c {
+        case '"':
+            inString, stringStart = true, i
+        case '{':
+            opens = append(opens, i)
+        case '}':
+            if n := len(opens); n > 0 {
+                closes[opens[n-1]] = i + 1
+                opens = opens[:n-1]
+            }
+        }
+    }
+
+    var objects [][2]int
+    for _, open := range enclosing {
+        if end, ok := closes[open]; ok {
+            objects = append(objects, [2]int{open, end})
+        }
+    }
+    return objects
+}
+
+// dockerAuthCredentials lists the credential fields of a decoded config, in
+// registry order. offset is where the config's JSON text starts.
+func dockerAuthCredentials(text string, offset int, config dockerConfig) []configCredential {
+    registries := make([]string, 0, len(config.Auths))
+    for registry := range config.Auths {
+        registries = append(registries, registry)
+    }
+    sort.Strings(registries)
+
+    var credentials []configCredential
+    for _, registry := range registries {
+        for _, field := range dockerAuthFields {
+            value, ok := config.Auths[registry][field].(string)
+            if !ok || !isCredentialValue(value) {
+                continue
+            }
+            // An escaped value is not found verbatim, the object start stands in
+            start := offset
+            if i := strings.Index(text, value); i != -1 {
+                start += i
+            }
+            credentials = append(credentials, configCredential{key: fmt.Sprintf("auths.%s.%s", registry, field), value: value, start: start})
+        }
+    }
+    return credentials
+}
@@ -0,0 +1,143 @@
+package detector
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Bounds on encoded payload scanning so a hostile message cannot blow up a scan
+const (
+    maxDecodeDepth     = 3
+    maxEncodedBlobs    = 64
+    maxEncodedBlobSize = 64 * 1024
+)
+
+// Encoding names used in the decodedVia provenance chain
+const (
+    encodingBase64 = "base64"
+    encodingHex    = "hex"
+    encodingURL    = "url"
+)
+
+// blobDecoder finds candidate encoded blobs and decodes them
+type blobDecoder struct {
+    name    string
+    pattern *regexp.Regexp
+    decode  func(blob string) (string, bool)
+}
+
+var blobDecoders = []blobDecoder{
+    {
+        name:    encodingBase64,
+        pattern: regexp.MustCompile(`[A-Za-z0-9+/_-]{24,}={0,2}`),
+        decode:  decodeBase64Blob,
+    },
+    {
+        name:    encodingHex,
+        pattern: regexp.MustCompile(`\b(?:[0-9A-Fa-f]{2}){12,}\b`),
+        decode:  decodeHexBlob,
+    },
+    {
+        name:    encodingURL,
+        pattern: regexp.MustCompile(`[A-Za-z0-9._~:/?#\[\]@!$&'()*+,;=%-]*%[0-9A-Fa-f]{2}[A-Za-z0-9._~:/?#\[\]@!$&'()*+,;=%-]*`),
+        decode:  decodeURLBlob,
+    },
+}
+
+// scanEncoded looks for encoded blobs in content, decodes them and scans the
+// decoded text, recursing into nested encodings up to maxDecodeDepth. Matches
+// found inside a blob are reported at the blob's position in the original message.
+func (p *scanPass) scanEncoded(content string, toOriginal spanMapper, variant textVariant) {
+    if len(variant.decodedVia) >= maxDecodeDepth {
+        return
+    }
+
+    blobs := 0
+    for _, decoder := range blobDecoders {
+        for _, loc := range decoder.pattern.FindAllStringIndex(content, -1) {
+            if blobs >= maxEncodedBlobs {
+                return
+            }
+            blob := content[loc[0]:loc[1]]
+            if len(blob) > maxEncodedBlobSize {
+                continue
+            }
+            decoded, ok := decoder.decode(blob)
+            if !ok {
+                continue
+            }
+            blobs++
+
+            blobStart, blobEnd := toOriginal(loc[0], loc[1])
+            blobSpan := func(_, _ int) (int, int) {
+                return blobStart, blobEnd
+            }
+
+            chain := make([]string, len(variant.decodedVia), len(variant.decodedVia)+1)
+            copy(chain, variant.decodedVia)
+            decodedVariant := textVariant{decodedVia: append(chain, decoder.name)}
+
+            p.scanText(decoded, blobSpan, decodedVariant)
+            p.scanEncoded(decoded, blobSpan, decodedVariant)
+        }
+    }
+}
+
+func decodeBase64Blob(blob string) (string, bool) {
+    trimmed := strings.TrimRight(blob, "=")
+    if len(trimmed)%4 == 1 {
+        return "", false
+    }
+
+    encoding := base64.RawStdEncoding
+    if strings.ContainsAny(trimmed, "-_") {
+        if strings.ContainsAny(trimmed, "+/") {
+            return "", false
+        }
+        encoding = base64.RawURLEncoding
+    }
+
+    decoded, err := encoding.DecodeString(trimmed)
+    if err != nil {
+        return "", false
+    }
+    return string(decoded), isReadableText(string(decoded))
+}
+
+func decodeHexBlob(blob string) (string, bool) {
+    decoded, err := hex.DecodeString(blob)
+    if err != nil {
+        return "", false
+    }
+    return string(decoded), isReadableText(string(decoded))
+}
+
+func decodeURLBlob(blob string) (string, bool) {
+    decoded, err := url.PathUnescape(blob)
+    if err != nil || decoded == blob {
+        return "", false
+    }
+    return decoded, isReadableText(decoded)
+}
+
+// isReadableText reports whether decoded bytes look like text rather than
+// binary noise, which is what random tokens and hashes decode to
+func isReadableText(s string) bool {
+    if len(s) < 8 || !utf8.ValidString(s) {
+        return false
+    }
+
+    var printable, total int
+    for _, r := range s {
+        total++
+        if unicode.IsPrint(r) || unicode.IsSpace(r) {
+            printable++
+        }
+    }
+    return float64(printable)/float64(total) >= 0.95
+}
@@ -0,0 +1,145 @@
+package detector
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// entropyCharset is a token alphabet with the entropy (bits per character) a
+// random token over it is expected to exceed. Hex tops out at 4 bits, so its
+// threshold is lower than for base64.
+type entropyCharset struct {
+    name      string
+    pattern   *regexp.Regexp
+    threshold float64
+    minLength int
+}
+
+// Checked in order, the first charset that covers the whole token applies
+var entropyCharsets = []entropyCharset{
+    {name: "hex", pattern: regexp.MustCompile(`^[0-9a-fA-F]+$`), threshold: 3.0, minLength: 32},
+    {name: "alphanumeric", pattern: regexp.MustCompile(`^[A-Za-z0-9]+$`), threshold: 3.8, minLength: 20},
+    {name: "base64", pattern: regexp.MustCompile(`^[A-Za-z0-9+/_-]+={0,2}$`), threshold: 4.3, minLength: 24},
+}
+
+// Candidate tokens: runs of base64/hex characters, split on everything else
+var entropyTokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_=-]{20,}`)
+
+// A high-entropy token is only reported when one of these is nearby
+var entropyKeywords = []string{
+    "key", "token", "secret", "password", "passwd", "pwd", "credential",
+    "auth", "bearer", "api", "private", "signature", "session", "cookie",
+}
+
+// Well-known public identifiers that are random but not secret
+var entropyAllowlist = []*regexp.Regexp{
+    regexp.MustCompile(`^AC[0-9a-f]{32}$`), // Twilio account SID
+    // Base64 file contents (e.g. data:image/png;base64,...), recognised by the
+    // encoded magic number of PNG, JPEG, GIF, WebP and PDF files
+    regexp.MustCompile(`^(?:iVBORw0KGgo|/9j/|R0lGOD[dl]h|UklGR|JVBERi0)`),
+}
+
+// Hex tokens of these lengths are MD5, SHA-1, SHA-224, SHA-256, SHA-384 and
+// SHA-512 digests (commit IDs, checksums, fingerprints) far more often than keys
+var hexDigestLengths = map[int]bool{32: true, 40: true, 56: true, 64: true, 96: true, 128: true}
+
+// newHighEntropyPattern builds the synthetic rule used by entropy-only
+// discovery. It goes through the same false-positive, scoring and masking
+// path as rule pack patterns; its validator applies the entropy thresholds.
+func newHighEntropyPattern(calc *ConfidenceCalculator) SecretPattern {
+    return SecretPattern{
+        ID:             "high-entropy-string",
+        Name:           "High Entropy String",
+        Pattern:        entropyTokenPattern,
+        Severity:       "LOW",
+        Description:    "Random-looking token near a credential keyword, not matched by any rule",
+        Specificity:    0.3,
+        MinLength:      20,
+        MaxLength:      128,
+        Keywords:       entropyKeywords,
+        Allowlist:      entropyAllowlist,
+        RequireKeyword: true,
+        Validator: func(token string) ValidationResult {
+            return checkTokenEntropy(calc, token)
+        },
+    }
+}
+
+// checkTokenEntropy vetoes tokens below their charset's entropy threshold.
+// Passing tokens are reported as inconclusive: randomness alone proves nothing.
+func checkTokenEntropy(calc *ConfidenceCalculator, token string) ValidationResult {
+    var upper, lower, digit bool
+    for _, r := range token {
+        switch {
+        case r >= 'A' && r <= 'Z':
+            upper = true
+        case r >= 'a' && r <= 'z':
+            lower = true
+        case r >= '0' && r <= '9':
+            digit = true
+        }
+    }
+    // Words, numbers and identifiers in a single case are not random tokens
+    if !digit || !(upper || lower) {
+        return ValidationResult{Reason: "single character class", Veto: true}
+    }
+
+    for _, charset := range entropyCharsets {
+        if !charset.pattern.MatchString(token) {
+            continue
+        }
+        if charset.name == "hex" && hexDigestLengths[len(token)] {
+            return ValidationResult{Reason: "hex digest length", Veto: true}
+        }
+        entropy := calc.calculateShannonEntropy(token)
+        if len(token) < charset.minLength || entropy < charset.threshold {
+            return ValidationResult{Reason: "below entropy threshold", Veto: true}
+        }
+        return ValidationResult{
+            Reason:       fmt.Sprintf("%s token, %.2f bits/char (threshold %.1f)", charset.name, entropy, charset.threshold),
+            Inconclusive: true,
+        }
+    }
+    return ValidationResult{Reason: "unrecognized charset", Veto: true}
+}
+
+// scanEntropy flags high-entropy tokens that no rule matched. It runs in its
+// own pass so that a rule detection is never replaced by the generic type.
+func (p *scanPass) scanEntropy(content string) {
+    // Values a rule allowlists (e.g. published emulator keys) are known not to
+    // be secrets, whichever pass finds them
+    pattern := newHighEntropyPattern(p.confidenceCalc)
+    pattern.Allowlist = append([]*regexp.Regexp(nil), entropyAllowlist...)
+    for _, rule := range p.rules.patterns {
+        pattern.Allowlist = append(pattern.Allowlist, rule.Allowlist...)
+    }
+    entropyPass := &scanPass{
+        scanner:        p.scanner,
+        rules:          &ruleSet{patterns: []SecretPattern{pattern}},
+        msg:            p.msg,
+        normalized:     p.normalized,
+        parentText:     p.parentText,
+        confidenceCalc: p.confidenceCalc,
+        profiles:       p.profiles,
+        keywords:       p.keywords,
+        suppression:    p.suppression,
+    }
+    entropyPass.scanText(content, p.normalized.originalSpan, textVariant{})
+
+    for _, candidate := range entropyPass.detections {
+        covered := false
+        for _, detection := range p.detections {
+            // A match still waiting for its pair partner may be dropped later
+            if p.needsPartner[partnerKey(detection)] {
+                continue
+            }
+            if candidate.StartOffset < detection.EndOffset && detection.StartOffset < candidate.EndOffset {
+                covered = true
+                break
+            }
+        }
+        if !covered {
+            p.detections = append(p.detections, candidate)
+        }
+    }
+}
@@ -0,0 +1,323 @@
+package detector
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed keywords/*.yaml
+var defaultKeywordFiles embed.FS
+
+// KeywordDictionary translates the English context keywords used in scoring
+// into one other language. English keywords stay the reference: a translation
+// counts exactly as its English keyword does.
+type KeywordDictionary struct {
+    Language string              `yaml:"language" json:"language"`
+    Name     string              `yaml:"name" json:"name"`
+    Markers  []string            `yaml:"markers,flow" json:"markers,omitempty"`
+    Scripts  []string            `yaml:"scripts,flow" json:"scripts,omitempty"`
+    Ignore   []string            `yaml:"ignore,flow" json:"ignore,omitempty"`
+    Keywords map[string][]string `yaml:"keywords" json:"keywords"`
+}
+
+// Language detection thresholds: distinct marker words, or characters in one
+// of the language's scripts
+const (
+    minMarkerWords = 2
+    minScriptRunes = 2
+)
+
+// KeywordDictionaries are the languages the scanner recognizes, by code
+type KeywordDictionaries struct {
+    dictionaries map[string]*KeywordDictionary
+    languages    []string // sorted codes, for a stable detection order
+}
+
+// DefaultKeywordDictionaries returns the dictionaries shipped with the binary
+func DefaultKeywordDictionaries() *KeywordDictionaries {
+    files, err := defaultKeywordFiles.ReadDir("keywords")
+    if err != nil {
+        panic(fmt.Sprintf("built-in keyword dictionaries are missing: %v", err))
+    }
+
+    set := &KeywordDictionaries{dictionaries: make(map[string]*KeywordDictionary)}
+    for _, file := range files {
+        data, err := defaultKeywordFiles.ReadFile("keywords/" + file.Name())
+        if err == nil {
+            err = set.add(data)
+        }
+        if err != nil {
+            panic(fmt.Sprintf("built-in keyword dictionary %s is invalid: %v", file.Name(), err))
+        }
+    }
+    return set
+}
+
+// LoadKeywordDictionaries returns the built-in dictionaries extended by every
+// .yaml file in dir; a file for a built-in language replaces it. An empty dir
+// returns the built-in dictionaries.
+func LoadKeywordDictionaries(dir string) (*KeywordDictionaries, error) {
+    set := DefaultKeywordDictionaries()
+    if dir == "" {
+        return set, nil
+    }
+
+    paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+    if err != nil {
+        return nil, err
+    }
+    var errs []error
+    for _, path := range paths {
+        data, err := os.ReadFile(path)
+        if err == nil {
+            err = set.add(data)
+        }
+        if err != nil {
+            errs = append(errs, fmt.Errorf("keyword dictionary %s: %w", path, err))
+        }
+    }
+    if len(errs) > 0 {
+        return nil, errors.Join(errs...)
+    }
+    return set, nil
+}
+
+// add parses and validates one dictionary, lowercasing its words
+func (kd *KeywordDictionaries) add(data []byte) error {
+    var dictionary KeywordDictionary
+    if err := yaml.Unmarshal(data, &dictionary); err != nil {
+        return fmt.Errorf("parsing: %w", err)
+    }
+
+    var errs []string
+    if strings.TrimSpace(dictionary.Language) == "" {
+        errs = append(errs, "missing language")
+    }
+    if len(dictionary.Markers) == 0 && len(dictionary.Scripts) == 0 {
+        errs = append(errs, "needs markers or scripts to be detected")
+    }
+    for _, script := range dictionary.Scripts {
+        if unicode.Scripts[script] == nil {
+            errs = append(errs, fmt.Sprintf("unknown script %q", script))
+        }
+    }
+    if len(errs) > 0 {
+        return errors.New(strings.Join(errs, "; "))
+    }
+
+    lower := func(words []string) []string {
+        lowered := make([]string, 0, len(words))
+        for _, word := range words {
+            if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+                lowered = append(lowered, word)
+            }
+        }
+        return lowered
+    }
+    dictionary.Markers = lower(dictionary.Markers)
+    dictionary.Ignore = lower(dictionary.Ignore)
+    keywords := make(map[string][]string, len(dictionary.Keywords))
+    for keyword, words := range dictionary.Keywords {
+        keywords[strings.ToLower(keyword)] = lower(words)
+    }
+    dictionary.Keywords = keywords
+
+    if _, exists := kd.dictionaries[dictionary.Language]; !exists {
+        kd.languages = append(kd.languages, dictionary.Language)
+        sort.Strings(kd.languages)
+    }
+    kd.dictionaries[dictionary.Language] = &dictionary
+    return nil
+}
+
+// Languages returns the codes of the recognized languages
+func (kd *KeywordDictionaries) Languages() []string {
+    return append([]string(nil), kd.languages...)
+}
+
+// Detect returns the keywords for the languages used in text; English
+// keywords always apply
+func (kd *KeywordDictionaries) Detect(text string) *KeywordSet {
+    set := &KeywordSet{}
+    if kd == nil || text == "" {
+        return set
+    }
+
+    markers := make(map[string]bool)
+    for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+        markers[word] = true
+    }
+
+    for _, language := range kd.languages {
+        dictionary := kd.dictionaries[language]
+        if dictionary.detect(text, markers) {
+            set.dictionaries = append(set.dictionaries, dictionary)
+        }
+    }
+    return set
+}
+
+func (dictionary *KeywordDictionary) detect(text string, words map[string]bool) bool {
+    found := 0
+    for _, marker := range dictionary.Markers {
+        if words[marker] {
+            if found++; found >= minMarkerWords {
+                return true
+            }
+        }
+    }
+
+    if len(dictionary.Scripts) == 0 {
+        return false
+    }
+    tables := make([]*unicode.RangeTable, 0, len(dictionary.Scripts))
+    for _, script := range dictionary.Scripts {
+        tables = append(tables, unicode.Scripts[script])
+    }
+    runes := 0
+    for _, r := range text {
+        if unicode.IsOneOf(tables, r) {
+            if runes++; runes >= minScriptRunes {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// KeywordSet matches English context keywords and their translations in the
+// languages detected in one message. A nil set matches English only.
+type KeywordSet struct {
+    dictionaries []*KeywordDictionary
+}
+
+// Languages returns the codes of the detected languages other than English
+func (ks *KeywordSet) Languages() []string {
+    if ks == nil {
+        return nil
+    }
+    languages := make([]string, 0, len(ks.dictionaries))
+    for _, dictionary := range ks.dictionaries {
+        languages = append(languages, dictionary.Language)
+    }
+    return languages
+}
+
+// find reports whether lowerText contains keyword or one of its translations,
+// returning the word found; translations are shown with their English keyword
+func (ks *KeywordSet) find(lowerText, keyword string) (string, bool) {
+    return ks.lookup(lowerText, keyword, strings.Contains)
+}
+
+// findWord is find for whole words only, so "test" is not found in "latest"
+func (ks *KeywordSet) findWord(lowerText, keyword string) (string, bool) {
+    return ks.lookup(lowerText, keyword, containsWord)
+}
+
+func (ks *KeywordSet) lookup(lowerText, keyword string, contains func(text, word string) bool) (string, bool) {
+    if ks == nil {
+        return keyword, contains(lowerText, keyword)
+    }
+
+    ignored := false
+    for _, dictionary := range ks.dictionaries {
+        ignored = ignored || containsString(dictionary.Ignore, keyword)
+    }
+    if !ignored && contains(lowerText, keyword) {
+        return keyword, true
+    }
+    for _, dictionary := range ks.dictionaries {
+        for _, word := range dictionary.Keywords[keyword] {
+            if contains(lowerText, word) {
+                return fmt.Sprintf("%s (%s: %s)", word, dictionary.Language, keyword), true
+            }
+        }
+    }
+    return "", false
+}
+
+// containsWord reports whether text contains word as a whole word, or as a
+// whole word with a plural "s". Underscores, hyphens and other punctuation
+// separate words, so "test_key" contains "test" but "attestation" does not.
+func containsWord(text, word string) bool {
+    if word == "" {
+        return false
+    }
+    first, _ := utf8.DecodeRuneInString(word)
+    last, _ := utf8.DecodeLastRuneInString(word)
+
+    for offset := 0; offset < len(text); {
+        index := strings.Index(text[offset:], word)
+        if index < 0 {
+            return false
+        }
+        start := offset + index
+        end := start + len(word)
+        offset = start + 1
+
+        if isWordRune(first) {
+            if before, _ := utf8.DecodeLastRuneInString(text[:start]); isWordRune(before) {
+                continue
+            }
+        }
+        if isWordRune(last) {
+            if end < len(text) && text[end] == 's' && unicode.IsLetter(last) {
+                end++
+            }
+            if after, _ := utf8.DecodeRuneInString(text[end:]); isWordRune(after) {
+                continue
+            }
+        }
+        return true
+    }
+    return false
+}
+
+// isWordRune reports whether r continues a word. Scripts written without
+// spaces between words (Chinese, Japanese) have no word boundaries to find.
+func isWordRune(r rune) bool {
+    if r == utf8.RuneError || unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+        return false
+    }
+    return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// containsAny reports whether the context mentions any of the (lowercase) keywords
+func (ks *KeywordSet) containsAny(context string, keywords []string) bool {
+    lowerContext := strings.ToLower(context)
+    for _, keyword := range keywords {
+        if _, ok := ks.find(lowerContext, keyword); ok {
+            return true
+        }
+    }
+    return false
+}
+
+// SetKeywordDictionaries switches subsequent scans to a set of dictionaries,
+// nil restores the built-in ones
+func (s *SecretScanner) SetKeywordDictionaries(dictionaries *KeywordDictionaries) {
+    if dictionaries == nil {
+        dictionaries = defaultDictionaries
+    }
+    s.dictionaries.Store(dictionaries)
+}
+
+// keywordDictionaries returns the active dictionaries
+func (s *SecretScanner) keywordDictionaries() *KeywordDictionaries {
+    if dictionaries := s.dictionaries.Load(); dictionaries != nil {
+        return dictionaries
+    }
+    return defaultDictionaries
+}
+
+// Parsed once, the built-in dictionaries are immutable
+var defaultDictionaries = DefaultKeywordDictionaries()
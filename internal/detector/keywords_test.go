@@ -0,0 +1,117 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestKeywordDictionariesDetect(t *testing.T) {
+    tests := []struct {
+        name string
+        text string
+        want []string
+    }{
+        {name: "English", text: "here is the password for the staging box"},
+        {name: "German marker words", text: "das ist der Schlüssel für die Datenbank", want: []string{"de"}},
+        {name: "one marker word is not enough", text: "la password is in the vault"},
+        {name: "Japanese script", text: "パスワードはこちらです", want: []string{"ja"}},
+        {name: "Hindi script", text: "यह पासवर्ड है", want: []string{"hi"}},
+    }
+
+    dictionaries := DefaultKeywordDictionaries()
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := dictionaries.Detect(tt.text).Languages(); !slices.Equal(got, tt.want) {
+                t.Errorf("languages = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestKeywordSetFind(t *testing.T) {
+    tests := []struct {
+        name    string
+        text    string
+        keyword string
+        want    string // word reported, "" when not found
+    }{
+        {name: "English keyword", text: "aquí está el password", keyword: "password", want: "password"},
+        {name: "translation", text: "aquí está la contraseña del servidor", keyword: "password", want: "contraseña (es: password)"},
+        {name: "ignored English word", text: "esto es todo para el deploy", keyword: "todo"},
+        {name: "ignored word still found by translation", text: "esto está pendiente para el deploy", keyword: "todo", want: "pendiente (es: todo)"},
+        {name: "English only without the language", text: "the contraseña is set", keyword: "password"},
+    }
+
+    dictionaries := DefaultKeywordDictionaries()
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            lower := strings.ToLower(tt.text)
+            got, ok := dictionaries.Detect(tt.text).find(lower, tt.keyword)
+            if ok != (tt.want != "") || ok && got != tt.want {
+                t.Errorf("find = %q, %v, want %q", got, ok, tt.want)
+            }
+        })
+    }
+}
+
+func TestContainsWord(t *testing.T) {
+    tests := []struct {
+        text, word string
+        want       bool
+    }{
+        {text: "test_key", word: "test", want: true},
+        {text: "db-latest", word: "test"},
+        {text: "attestation", word: "test"},
+        {text: "sample tokens", word: "token", want: true},
+        {text: "tokenizer", word: "token"},
+        {text: "テスト用パスワード", word: "パスワード", want: true},
+        {text: "", word: "test"},
+    }
+
+    for _, tt := range tests {
+        if got := containsWord(tt.text, tt.word); got != tt.want {
+            t.Errorf("containsWord(%q, %q) = %v, want %v", tt.text, tt.word, got, tt.want)
+        }
+    }
+}
+
+func TestLoadKeywordDictionaries(t *testing.T) {
+    tests := []struct {
+        name    string
+        data    string
+        want    []string // languages after loading
+        wantErr string   // "" when the file is accepted
+    }{
+        {name: "new language", data: "language: fr\nmarkers: [le, la, les, est]\nkeywords:\n  password: [mot de passe]\n", want: []string{"de", "es", "fr", "hi", "ja"}},
+        {name: "replaces a built-in language", data: "language: es\nmarkers: [el, la]\nkeywords: {}\n", want: []string{"de", "es", "hi", "ja"}},
+        {name: "no language", data: "markers: [le, la]\n", wantErr: "missing language"},
+        {name: "not detectable", data: "language: fr\n", wantErr: "needs markers or scripts"},
+        {name: "unknown script", data: "language: el\nscripts: [Greekish]\n", wantErr: `unknown script "Greekish"`},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            dir := t.TempDir()
+            if err := os.WriteFile(filepath.Join(dir, "extra.yaml"), []byte(tt.data), 0o644); err != nil {
+                t.Fatal(err)
+            }
+
+            dictionaries, err := LoadKeywordDictionaries(dir)
+            if tt.wantErr != "" {
+                if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+                    t.Errorf("error = %v, want %q", err, tt.wantErr)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := dictionaries.Languages(); !slices.Equal(got, tt.want) {
+                t.Errorf("languages = %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,84 @@
+package detector
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+    ErrRuleNotFound = errors.New("rule not found")
+    ErrRuleExists   = errors.New("rule with this id already exists")
+)
+
+// RulePack returns a copy of the active rule pack, including disabled rules
+func (s *SecretScanner) RulePack() *RulePack {
+    return s.rules.Load().pack.Clone()
+}
+
+// WithRulePack returns a scanner running pack with this scanner's entropy,
+// scoring, keyword and suppression settings, so a dry-run scores like production
+func (s *SecretScanner) WithRulePack(pack *RulePack) (*SecretScanner, error) {
+    scanner, err := NewSecretScannerFromPack(pack)
+    if err != nil {
+        return nil, err
+    }
+    scanner.entropyScan.Store(s.entropyScan.Load())
+    scanner.calibration.Store(s.calibration.Load())
+    scanner.profiles.Store(s.profiles.Load())
+    scanner.dictionaries.Store(s.dictionaries.Load())
+    scanner.suppression.Store(s.suppression.Load())
+    return scanner, nil
+}
+
+// AddRule validates a new rule and activates it for subsequent scans
+func (s *SecretScanner) AddRule(rule RuleDefinition) error {
+    return s.updateRules(func(pack *RulePack) error {
+        for _, existing := range pack.Rules {
+            if existing.ID == rule.ID {
+                return fmt.Errorf("%w: %s", ErrRuleExists, rule.ID)
+            }
+        }
+        pack.Rules = append(pack.Rules, rule)
+        return nil
+    })
+}
+
+// SetRuleEnabled enables or disables an existing rule
+func (s *SecretScanner) SetRuleEnabled(id string, enabled bool) error {
+    return s.updateRules(func(pack *RulePack) error {
+        for i := range pack.Rules {
+            if pack.Rules[i].ID == id {
+                pack.Rules[i].Disabled = !enabled
+                return nil
+            }
+        }
+        return fmt.Errorf("%w: %s", ErrRuleNotFound, id)
+    })
+}
+
+// DeleteRule removes a rule from the active pack
+func (s *SecretScanner) DeleteRule(id string) error {
+    return s.updateRules(func(pack *RulePack) error {
+        for i := range pack.Rules {
+            if pack.Rules[i].ID == id {
+                pack.Rules = append(pack.Rules[:i], pack.Rules[i+1:]...)
+                return nil
+            }
+        }
+        return fmt.Errorf("%w: %s", ErrRuleNotFound, id)
+    })
+}
+
+// updateRules applies a modification to a copy of the active pack and swaps
+// it in only if the result still compiles. Runtime changes live in memory and
+// are replaced by the next reload of the rule file.
+func (s *SecretScanner) updateRules(modify func(pack *RulePack) error) error {
+    s.updateMutex.Lock()
+    defer s.updateMutex.Unlock()
+
+    pack := s.rules.Load().pack.Clone()
+    if err := modify(pack); err != nil {
+        return err
+    }
+    return s.swapRules(pack)
+}
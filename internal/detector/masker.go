@@ -19,8 +19,13 @@ func maskSecret(secret string) string {
 		return maskURLSecret(secret)
 	}
 	
-	// Handle JWT secrets
-	if strings.Count(secret, ".") == 2 && len(secret) > 50 {
+	// Handle tokens whose family prefix identifies the issuer
+	if prefix := tokenFamilyPrefix(secret); prefix != "" {
+		return prefix + maskSingleLineSecret(secret[len(prefix):])
+	}
+	
+	// Handle JWT secrets (other dotted tokens such as SendGrid "SG." keys are not JWTs)
+	if strings.HasPrefix(secret, "eyJ") && strings.Count(secret, ".") == 2 && len(secret) > 50 {
 		return maskJWTSecret(secret)
 	}
 	
@@ -28,19 +33,26 @@ func maskSecret(secret string) string {
 	return maskSingleLineSecret(secret)
 }
 
+// maskFullSecret hides a value completely, without revealing its length
+func maskFullSecret(secret string) string {
+	return "********"
+}
+
 func maskMultiLineSecret(secret string) string {
 	lines := strings.Split(secret, "\n")
 	var maskedLines []string
+	shownContent := false
 	
-	for i, line := range lines {
+	for _, line := range lines {
 		line = strings.TrimSpace(line)
 		
-		if line == "" || strings.HasPrefix(line, "-----") || strings.HasPrefix(line, "Comment:") {
+		if line == "" || strings.HasPrefix(line, "-----") || isArmorHeader(line) {
 			maskedLines = append(maskedLines, line)
-		} else if i == 1 && len(line) > 8 {
+		} else if !shownContent && len(line) > 8 {
 			// Show beginning of first content line for identification
 			visibleChars := min(8, len(line)/3)
 			maskedLines = append(maskedLines, line[:visibleChars]+"***[REDACTED]***")
+			shownContent = true
 		} else {
 			maskedLines = append(maskedLines, "***[REDACTED]***")
 		}
@@ -49,6 +61,33 @@ func maskMultiLineSecret(secret string) string {
 	return strings.Join(maskedLines, "\n")
 }
 
+// isArmorHeader reports PEM/PGP armor headers such as "Comment:", "Version:" or "Proc-Type:"
+func isArmorHeader(line string) bool {
+	for _, header := range []string{"Comment:", "Version:", "Hash:", "Proc-Type:", "DEK-Info:", "Charset:"} {
+		if strings.HasPrefix(line, header) {
+			return true
+		}
+	}
+	return false
+}
+
+// Token family prefixes that are kept visible for triage. They carry no secret
+// material; longer prefixes come first so "github_pat_" wins over shorter ones.
+var tokenFamilyPrefixes = []string{
+	"github_pat_", "ghp_", "gho_", "ghu_", "ghs_", "ghr_",
+	"glpat-", "glrt-", "gldt-",
+	"sk_live_", "rk_live_", "dckr_pat_", "npm_", "pypi-",
+}
+
+func tokenFamilyPrefix(secret string) string {
+	for _, prefix := range tokenFamilyPrefixes {
+		if strings.HasPrefix(secret, prefix) && len(secret)-len(prefix) > 8 {
+			return prefix
+		}
+	}
+	return ""
+}
+
 func maskURLSecret(secret string) string {
 	parts := strings.SplitN(secret, "://", 2)
 	if len(parts) != 2 {
@@ -0,0 +1,206 @@
+package detector
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// normalizedText is scanner input derived from a message body, together with
+// a byte-level map back to the original body so matches can be located there
+type normalizedText struct {
+    text     string
+    original string
+    starts   []int // starts[i] is the original offset of the unit that produced text[i]
+    ends     []int // ends[i] is the original end offset of that unit
+    regions  []textRegion
+}
+
+// Kinds of regions recorded while normalizing text
+const (
+    regionCodeBlock    = "code"
+    regionLinkHref     = "href"
+    regionDeobfuscated = "deobfuscated" // invisible or look-alike characters were folded
+)
+
+// textRegion marks an [start, end) span of the original body with its markup kind
+type textRegion struct {
+    kind  string
+    start int
+    end   int
+}
+
+// textBuilder accumulates normalized bytes and their original spans
+type textBuilder struct {
+    buf     strings.Builder
+    starts  []int
+    ends    []int
+    regions []textRegion
+}
+
+func (tb *textBuilder) writeString(s string, start, end int) {
+    for i := 0; i < len(s); i++ {
+        tb.buf.WriteByte(s[i])
+        tb.starts = append(tb.starts, start)
+        tb.ends = append(tb.ends, end)
+    }
+}
+
+func (tb *textBuilder) build(original string) normalizedText {
+    return normalizedText{
+        text:     tb.buf.String(),
+        original: original,
+        starts:   tb.starts,
+        ends:     tb.ends,
+        regions:  tb.regions,
+    }
+}
+
+// markRegion records a region, extending the previous one when they are contiguous
+func (tb *textBuilder) markRegion(kind string, start, end int) {
+    if n := len(tb.regions); n > 0 && tb.regions[n-1].kind == kind && tb.regions[n-1].end >= start {
+        if end > tb.regions[n-1].end {
+            tb.regions[n-1].end = end
+        }
+        return
+    }
+    tb.regions = append(tb.regions, textRegion{kind: kind, start: start, end: end})
+}
+
+// originalSpan maps a normalized [start, end) range back to the original body
+func (nt normalizedText) originalSpan(start, end int) (int, int) {
+    if len(nt.starts) == 0 || start >= end {
+        return 0, 0
+    }
+    return nt.starts[start], nt.ends[end-1]
+}
+
+// traitsAt reports the markup regions an original [start, end) span falls in
+func (nt normalizedText) traitsAt(start, end int) MatchTraits {
+    var traits MatchTraits
+    for _, region := range nt.regions {
+        if start >= region.end || region.start >= end {
+            continue
+        }
+        switch region.kind {
+        case regionCodeBlock:
+            traits.InCodeBlock = true
+        case regionLinkHref:
+            traits.InLinkHref = true
+        case regionDeobfuscated:
+            traits.Deobfuscated = true
+        }
+    }
+    return traits
+}
+
+// lineColumn returns the 1-based line and rune column of an original offset
+func (nt normalizedText) lineColumn(offset int) (int, int) {
+    if offset > len(nt.original) {
+        offset = len(nt.original)
+    }
+    before := nt.original[:offset]
+    line := strings.Count(before, "\n") + 1
+    lineStart := strings.LastIndex(before, "\n") + 1
+    return line, utf8.RuneCountInString(before[lineStart:]) + 1
+}
+
+// decodeEscapes decodes HTML entities (Teams messages might contain &lt;, &gt;, etc.)
+// and JSON-escaped control characters, keeping each decoded byte mapped to the
+// escape sequence it came from
+func decodeEscapes(content string) normalizedText {
+    var tb textBuilder
+    tb.writeDecoded(content, 0)
+    return tb.build(content)
+}
+
+// writeDecoded decodes content (which starts at original offset base) into the builder
+func (tb *textBuilder) writeDecoded(content string, base int) {
+    for i := 0; i < len(content); {
+        switch {
+        case content[i] == '&':
+            // The entity ends at the first ';', without another '&' before it
+            if semi := strings.IndexByte(content[i:min(len(content), i+12)], ';'); semi > 1 && !strings.ContainsRune(content[i+1:i+semi], '&') {
+                entity := content[i : i+semi+1]
+                if decoded := html.UnescapeString(entity); decoded != entity {
+                    tb.writeString(decoded, base+i, base+i+semi+1)
+                    i += semi + 1
+                    continue
+                }
+            }
+        case content[i] == '\\' && i+1 < len(content):
+            // Convert JSON-escaped newlines to actual newlines
+            if replacement, ok := jsonEscapes[content[i+1]]; ok {
+                tb.writeString(replacement, base+i, base+i+2)
+                i += 2
+                continue
+            }
+        }
+        tb.writeString(content[i:i+1], base+i, base+i+1)
+        i++
+    }
+}
+
+var jsonEscapes = map[byte]string{
+    'n': "\n",
+    'r': "\r",
+    't': "\t",
+}
+
+// normalizeWhitespace folds every kind of whitespace (including Unicode spaces
+// such as NBSP and em space) so secrets split by unusual whitespace still
+// match, while keeping line breaks so multi-line formats (PEM, PGP, kubeconfig,
+// .env blocks) keep their structure. Horizontal runs become a single space;
+// runs containing line breaks become one "\n" per break, followed by a single
+// space if the next line was indented.
+func normalizeWhitespace(in normalizedText) normalizedText {
+    var tb textBuilder
+    tb.regions = in.regions
+
+    for i := 0; i < len(in.text); {
+        r, size := utf8.DecodeRuneInString(in.text[i:])
+        if !unicode.IsSpace(r) {
+            tb.writeString(in.text[i:i+size], in.starts[i], in.ends[i+size-1])
+            i += size
+            continue
+        }
+
+        // Consume the whole whitespace run, emitting line breaks as they occur
+        indentStart := -1
+        for i < len(in.text) {
+            r, size = utf8.DecodeRuneInString(in.text[i:])
+            if !unicode.IsSpace(r) {
+                break
+            }
+            switch {
+            case r == '\r' && i+1 < len(in.text) && in.text[i+1] == '\n':
+                // CRLF counts as a single break
+                tb.writeString("\n", in.starts[i], in.ends[i+1])
+                indentStart = -1
+                size = 2
+            case isLineBreak(r):
+                tb.writeString("\n", in.starts[i], in.ends[i+size-1])
+                indentStart = -1
+            case indentStart == -1:
+                indentStart = i
+            }
+            i += size
+        }
+
+        if indentStart != -1 {
+            tb.writeString(" ", in.starts[indentStart], in.ends[i-1])
+        }
+    }
+
+    return tb.build(in.original)
+}
+
+// isLineBreak covers ASCII and Unicode line terminators (NEL, LINE/PARAGRAPH SEPARATOR)
+func isLineBreak(r rune) bool {
+    switch r {
+    case '\n', '\r', '\v', '\f', 0x85, 0x2028, 0x2029:
+        return true
+    }
+    return false
+}
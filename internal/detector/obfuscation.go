@@ -0,0 +1,142 @@
+package detector
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Invisible code points people insert to break up secrets
+var invisibleRunes = map[rune]bool{
+    '\u00AD': true, // soft hyphen
+    '\u034F': true, // combining grapheme joiner
+    '\u180E': true, // mongolian vowel separator
+    '\u200B': true, // zero width space
+    '\u200C': true, // zero width non-joiner
+    '\u200D': true, // zero width joiner
+    '\u200E': true, // left-to-right mark
+    '\u200F': true, // right-to-left mark
+    '\u2060': true, // word joiner
+    '\u2061': true, // function application
+    '\u2062': true, // invisible times
+    '\u2063': true, // invisible separator
+    '\u2064': true, // invisible plus
+    '\uFEFF': true, // zero width no-break space / BOM
+}
+
+// Cyrillic and Greek letters that render like ASCII
+var confusableRunes = map[rune]rune{
+    // Cyrillic
+    '\u0410': 'A', '\u0412': 'B', '\u0415': 'E', '\u041A': 'K', '\u041C': 'M', '\u041D': 'H', '\u041E': 'O',
+    '\u0420': 'P', '\u0421': 'C', '\u0422': 'T', '\u0425': 'X', '\u0423': 'Y', '\u0406': 'I', '\u0408': 'J',
+    '\u0405': 'S', '\u0430': 'a', '\u0435': 'e', '\u043E': 'o', '\u0440': 'p', '\u0441': 'c', '\u0443': 'y',
+    '\u0445': 'x', '\u0456': 'i', '\u0458': 'j', '\u0455': 's', '\u04BB': 'h', '\u0501': 'd', '\u051B': 'q',
+    '\u051D': 'w',
+    // Greek
+    '\u0391': 'A', '\u0392': 'B', '\u0395': 'E', '\u0396': 'Z', '\u0397': 'H', '\u0399': 'I', '\u039A': 'K',
+    '\u039C': 'M', '\u039D': 'N', '\u039F': 'O', '\u03A1': 'P', '\u03A4': 'T', '\u03A5': 'Y', '\u03A7': 'X',
+    '\u03BF': 'o', '\u03BD': 'v', '\u03B9': 'i',
+}
+
+// foldConfusables removes invisible code points and folds look-alike
+// characters (Cyrillic/Greek homoglyphs, fullwidth ASCII) to plain ASCII.
+// Every changed position is recorded as a deobfuscated region.
+func foldConfusables(in normalizedText) normalizedText {
+    var tb textBuilder
+    tb.regions = append(tb.regions, in.regions...)
+
+    for i := 0; i < len(in.text); {
+        r, size := utf8.DecodeRuneInString(in.text[i:])
+        start, end := in.starts[i], in.ends[i+size-1]
+
+        switch {
+        case invisibleRunes[r]:
+            tb.markRegion(regionDeobfuscated, start, end)
+        case r >= '\uFF01' && r <= '\uFF5E':
+            // Fullwidth forms map 1:1 onto printable ASCII
+            tb.writeString(string(r-0xFEE0), start, end)
+            tb.markRegion(regionDeobfuscated, start, end)
+        case confusableRunes[r] != 0:
+            tb.writeString(string(confusableRunes[r]), start, end)
+            tb.markRegion(regionDeobfuscated, start, end)
+        default:
+            tb.writeString(in.text[i:i+size], start, end)
+        }
+        i += size
+    }
+
+    return tb.build(in.original)
+}
+
+// Runs of short token-like groups separated by single spaces, e.g. "AKIA IOSF ODNN 7EXA"
+var spacedRunPattern = regexp.MustCompile(`(?:[A-Za-z0-9+/=_.-]{1,8} ){3,}[A-Za-z0-9+/=_.-]{1,8}`)
+
+// despacedRun is a candidate region with its separating spaces removed
+type despacedRun struct {
+    text    string
+    offsets []int // offsets[i] is the position of text[i] in the scanned content
+}
+
+// despacedRuns finds regions that look like a secret pasted with a space every
+// few characters. Consecutive groups must share one length (the last may be
+// shorter) so ordinary prose is not glued together.
+func despacedRuns(content string) []despacedRun {
+    var runs []despacedRun
+
+    for _, loc := range spacedRunPattern.FindAllStringIndex(content, -1) {
+        // Group boundaries as [start, end) positions in content
+        var groups [][2]int
+        groupStart := loc[0]
+        for i := loc[0]; i <= loc[1]; i++ {
+            if i == loc[1] || content[i] == ' ' {
+                groups = append(groups, [2]int{groupStart, i})
+                groupStart = i + 1
+            }
+        }
+
+        for first := 0; first < len(groups); {
+            groupLen := groups[first][1] - groups[first][0]
+            last := first
+            for last+1 < len(groups) && groups[last+1][1]-groups[last+1][0] == groupLen {
+                last++
+            }
+            // A trailing shorter group is the remainder of the secret
+            if last+1 < len(groups) && groups[last+1][1]-groups[last+1][0] < groupLen {
+                last++
+            }
+
+            if last-first+1 >= 4 {
+                if run := joinGroups(content, groups[first:last+1]); len(run.text) >= 16 {
+                    runs = append(runs, run)
+                }
+            }
+            first = last + 1
+        }
+    }
+
+    return runs
+}
+
+func joinGroups(content string, groups [][2]int) despacedRun {
+    var run despacedRun
+    var sb strings.Builder
+    for _, group := range groups {
+        for i := group[0]; i < group[1]; i++ {
+            sb.WriteByte(content[i])
+            run.offsets = append(run.offsets, i)
+        }
+    }
+    run.text = sb.String()
+    return run
+}
+
+// scanDespaced scans the de-spaced variant of every spaced-out candidate region
+func (p *scanPass) scanDespaced(content string) {
+    for _, run := range despacedRuns(content) {
+        offsets := run.offsets
+        toOriginal := func(start, end int) (int, int) {
+            return p.normalized.originalSpan(offsets[start], offsets[end-1]+1)
+        }
+        p.scanText(run.text, toOriginal, textVariant{deobfuscated: true})
+    }
+}
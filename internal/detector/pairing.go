@@ -0,0 +1,147 @@
+package detector
+
+import (
+	"math"
+
+	"stackguard-task/internal/models"
+)
+
+// maxPairGap is how many bytes may separate the two halves of a pair; a
+// credentials file or a sentence keeps them close, while an access key ID and
+// an unrelated 40-character value further down the message are not a pair
+const maxPairGap = 200
+
+// anchoredDetections drops matches that were only kept in case a pair partner
+// turned up (no keywords nearby) when the message has no such partner close by
+func (p *scanPass) anchoredDetections() []models.SecretDetection {
+    if len(p.needsPartner) == 0 {
+        return p.detections
+    }
+
+    var kept []models.SecretDetection
+    for _, detection := range p.detections {
+        if p.needsPartner[partnerKey(detection)] && !p.hasPartner(detection, p.detections) {
+            if pattern, ok := p.rules.pattern(detection.RuleID); ok {
+                p.suppress(*pattern, detection.FullValue, detection.Context, detection.StartOffset,
+                    SuppressedByNoKeyword, "", detection.Confidence)
+            }
+            continue
+        }
+        kept = append(kept, detection)
+    }
+    return kept
+}
+
+// partnerKey identifies a detection of a specific rule; detection IDs alone
+// collide when two rules report the same value at the same offset
+func partnerKey(detection models.SecretDetection) string {
+    return detection.RuleID + "/" + detection.ID
+}
+
+func (p *scanPass) hasPartner(detection models.SecretDetection, detections []models.SecretDetection) bool {
+    pattern, ok := p.rules.pattern(detection.RuleID)
+    if !ok || pattern.Pair == nil {
+        return false
+    }
+    for _, candidate := range detections {
+        if containsString(pattern.Pair.With, candidate.RuleID) && pairGap(detection, candidate) <= maxPairGap {
+            return true
+        }
+    }
+    return false
+}
+
+// pairDetections replaces each detection of a pairing rule and the nearest
+// unpaired detection of one of its partner rules, no more than maxPairGap
+// away, with a single combined detection
+func (p *scanPass) pairDetections(detections []models.SecretDetection) []models.SecretDetection {
+    paired := make(map[int]bool)
+    var combined []models.SecretDetection
+
+    for i, detection := range detections {
+        pattern, ok := p.rules.pattern(detection.RuleID)
+        if !ok || pattern.Pair == nil || paired[i] {
+            continue
+        }
+
+        partner := -1
+        for j, candidate := range detections {
+            if paired[j] || j == i || !containsString(pattern.Pair.With, candidate.RuleID) || pairGap(detection, candidate) > maxPairGap {
+                continue
+            }
+            if partner == -1 || pairGap(detection, candidate) < pairGap(detection, detections[partner]) {
+                partner = j
+            }
+        }
+        if partner == -1 {
+            continue
+        }
+
+        paired[i], paired[partner] = true, true
+        combined = append(combined, p.combineDetections(*pattern.Pair, detection, detections[partner]))
+    }
+
+    result := combined
+    for i, detection := range detections {
+        if !paired[i] {
+            result = append(result, detection)
+        }
+    }
+    return result
+}
+
+// combineDetections builds the pair detection; the partner (e.g. the access
+// key ID) comes first in the masked and full values
+func (p *scanPass) combineDetections(pairing RulePairing, detection, partner models.SecretDetection) models.SecretDetection {
+    first, second := partner, detection
+    if detection.StartOffset < partner.StartOffset {
+        first, second = detection, partner
+    }
+
+    pair := first
+    pair.SecretType = pairing.Name
+    pair.RuleID = detection.RuleID
+    pair.Severity = pairing.Severity
+    pair.MaskedValue = partner.MaskedValue + " / " + detection.MaskedValue
+    pair.FullValue = partner.FullValue + "\n" + detection.FullValue
+    pair.StartOffset = min(detection.StartOffset, partner.StartOffset)
+    pair.EndOffset = max(detection.EndOffset, partner.EndOffset)
+    pair.ID = generateDetectionID(p.msg.ID, pair.FullValue, pair.StartOffset)
+
+    // Either half being real makes the pair real
+    pair.Confidence = math.Min(1.0, 1-(1-detection.Confidence)*(1-partner.Confidence))
+    if first.Explanation != nil {
+        // Explain the pair from its first half, plus the boost from the second
+        explanation := *first.Explanation
+        explanation.Adjustments = append(append([]models.ConfidenceAdjustment(nil), explanation.Adjustments...), models.ConfidenceAdjustment{
+            Factor:  "confidence",
+            Reason:  "paired with " + second.SecretType,
+            Trigger: pairing.Name,
+            Before:  first.Confidence,
+            After:   pair.Confidence,
+        })
+        explanation.Confidence = pair.Confidence
+        pair.Explanation = &explanation
+    }
+    pair.Validated = detection.Validated || partner.Validated
+    pair.Deobfuscated = detection.Deobfuscated || partner.Deobfuscated
+    pair.InCodeBlock = detection.InCodeBlock || partner.InCodeBlock
+    return pair
+}
+
+// pairGap is the number of bytes between two detections, 0 when they touch or overlap
+func pairGap(a, b models.SecretDetection) int {
+    if a.StartOffset > b.StartOffset {
+        a, b = b, a
+    }
+    return max(0, b.StartOffset-a.EndOffset)
+}
+
+func containsString(values []string, target string) bool {
+    for _, value := range values {
+        if value == target {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,228 @@
+package detector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultProfileName is the profile used when no rule, channel or team binding applies
+const DefaultProfileName = "default"
+
+// ProfilesFile is the on-disk form of scoring profiles and where they apply.
+// A profile that leaves a setting out inherits it from the built-in default.
+//
+//   profiles:
+//     strict:  {minConfidence: 0.2, alertThreshold: 0.3}
+//     lenient: {minConfidence: 0.6, alertThreshold: 0.9, entropyThreshold: 3.5}
+//   channels: {"19:prod-ops@thread.tacv2": strict}
+//   teams:    {"security-training-team": lenient}
+//   rules:    {generic-api-key: lenient}
+type ProfilesFile struct {
+    Profiles map[string]ProfileDefinition `yaml:"profiles" json:"profiles"`
+    Rules    map[string]string            `yaml:"rules,omitempty" json:"rules,omitempty"`
+    Channels map[string]string            `yaml:"channels,omitempty" json:"channels,omitempty"`
+    Teams    map[string]string            `yaml:"teams,omitempty" json:"teams,omitempty"`
+}
+
+// ProfileDefinition is the on-disk form of a ScoringProfile, unset fields are nil
+type ProfileDefinition struct {
+    Weights          map[string]float64 `yaml:"weights,omitempty" json:"weights,omitempty"`
+    EntropyThreshold *float64           `yaml:"entropyThreshold,omitempty" json:"entropyThreshold,omitempty"`
+    MinConfidence    *float64           `yaml:"minConfidence,omitempty" json:"minConfidence,omitempty"`
+    AlertThreshold   *float64           `yaml:"alertThreshold,omitempty" json:"alertThreshold,omitempty"`
+}
+
+// ScoringProfile tunes scoring and reporting for the rules, channels or teams bound to it
+type ScoringProfile struct {
+    Name string `json:"name"`
+    // Weights override factor weights, including learned ones; factors left
+    // out keep the learned or default weight
+    Weights map[string]float64 `json:"weights,omitempty"`
+    // EntropyThreshold (bits/char) penalizes matched values that are less
+    // random than this, 0 disables the check
+    EntropyThreshold float64 `json:"entropyThreshold"`
+    MinConfidence    float64 `json:"minConfidence"`  // matches scoring below this are dropped
+    AlertThreshold   float64 `json:"alertThreshold"` // detections scoring below this are stored but not alerted on
+}
+
+// Built-in default: the scanner's long-standing 0.3 cut-off, alerts on every detection
+var defaultProfile = ScoringProfile{
+    Name:          DefaultProfileName,
+    MinConfidence: 0.3,
+}
+
+// ProfileSet is a validated set of profiles with their bindings
+type ProfileSet struct {
+    profiles map[string]*ScoringProfile
+    rules    map[string]string
+    channels map[string]string
+    teams    map[string]string
+}
+
+// DefaultProfiles returns a set holding only the built-in default profile
+func DefaultProfiles() *ProfileSet {
+    profile := defaultProfile
+    return &ProfileSet{profiles: map[string]*ScoringProfile{DefaultProfileName: &profile}}
+}
+
+// LoadProfiles reads and validates a profiles file, or returns the built-in
+// default when path is empty
+func LoadProfiles(path string) (*ProfileSet, error) {
+    if path == "" {
+        return DefaultProfiles(), nil
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("reading scoring profiles %s: %w", path, err)
+    }
+
+    var file ProfilesFile
+    if err := yaml.Unmarshal(data, &file); err != nil {
+        return nil, fmt.Errorf("parsing scoring profiles %s: %w", path, err)
+    }
+    set, err := file.Compile()
+    if err != nil {
+        return nil, fmt.Errorf("scoring profiles %s: %w", path, err)
+    }
+    return set, nil
+}
+
+// Compile validates the file and fills in unset profile settings from the
+// built-in default. All problems are reported together.
+func (pf ProfilesFile) Compile() (*ProfileSet, error) {
+    var errs []error
+    set := DefaultProfiles()
+
+    names := make([]string, 0, len(pf.Profiles))
+    for name := range pf.Profiles {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+
+    for _, name := range names {
+        profile, err := pf.Profiles[name].compile(name)
+        if err != nil {
+            errs = append(errs, fmt.Errorf("profile %s: %w", name, err))
+            continue
+        }
+        set.profiles[name] = profile
+    }
+
+    bind := func(kind string, bindings map[string]string) map[string]string {
+        keys := make([]string, 0, len(bindings))
+        for key := range bindings {
+            keys = append(keys, key)
+        }
+        sort.Strings(keys)
+        for _, key := range keys {
+            name := bindings[key]
+            if _, ok := pf.Profiles[name]; !ok && name != DefaultProfileName {
+                errs = append(errs, fmt.Errorf("%s %s: unknown profile %q", kind, key, name))
+            }
+        }
+        return bindings
+    }
+    set.rules = bind("rule", pf.Rules)
+    set.channels = bind("channel", pf.Channels)
+    set.teams = bind("team", pf.Teams)
+
+    if len(errs) > 0 {
+        return nil, errors.Join(errs...)
+    }
+    return set, nil
+}
+
+func (pd ProfileDefinition) compile(name string) (*ScoringProfile, error) {
+    var errs []string
+    profile := defaultProfile
+    profile.Name = name
+
+    if len(pd.Weights) > 0 {
+        factors := make([]string, 0, len(pd.Weights))
+        for factor := range pd.Weights {
+            factors = append(factors, factor)
+        }
+        sort.Strings(factors)
+
+        var total float64
+        for _, factor := range factors {
+            weight := pd.Weights[factor]
+            if _, ok := defaultFactorWeights[factor]; !ok {
+                errs = append(errs, fmt.Sprintf("unknown factor %q (must be one of %s)", factor, strings.Join(confidenceFactorNames, ", ")))
+            } else if weight < 0 {
+                errs = append(errs, fmt.Sprintf("negative weight for %s", factor))
+            } else {
+                total += weight
+            }
+        }
+        if total <= 0 {
+            errs = append(errs, "weights must not all be zero")
+        }
+        profile.Weights = pd.Weights
+    }
+
+    inRange := func(field string, value *float64, max float64, target *float64) {
+        if value == nil {
+            return
+        }
+        if *value < 0 || *value > max {
+            errs = append(errs, fmt.Sprintf("%s %.2f out of range 0.0 - %.1f", field, *value, max))
+            return
+        }
+        *target = *value
+    }
+    inRange("entropyThreshold", pd.EntropyThreshold, 8, &profile.EntropyThreshold)
+    inRange("minConfidence", pd.MinConfidence, 1, &profile.MinConfidence)
+    inRange("alertThreshold", pd.AlertThreshold, 1, &profile.AlertThreshold)
+
+    if len(errs) > 0 {
+        return nil, errors.New(strings.Join(errs, "; "))
+    }
+    return &profile, nil
+}
+
+// Resolve picks the profile for a match of ruleID in a channel of a team. The
+// most specific binding wins: channel, then team, then rule, then the default.
+func (ps *ProfileSet) Resolve(ruleID, channelID, teamID string) *ScoringProfile {
+    for _, name := range []string{ps.channels[channelID], ps.teams[teamID], ps.rules[ruleID]} {
+        if profile, ok := ps.profiles[name]; ok && name != "" {
+            return profile
+        }
+    }
+    return ps.profiles[DefaultProfileName]
+}
+
+// SetProfiles switches subsequent scans to a profile set, nil restores the built-in default
+func (s *SecretScanner) SetProfiles(profiles *ProfileSet) {
+    if profiles == nil {
+        profiles = DefaultProfiles()
+    }
+    s.profiles.Store(profiles)
+}
+
+// Profile returns the scoring profile that applies to a rule in a channel of a team
+func (s *SecretScanner) Profile(ruleID, channelID, teamID string) *ScoringProfile {
+    return s.scoringProfiles().Resolve(ruleID, channelID, teamID)
+}
+
+// scoringProfiles returns the active profile set
+func (s *SecretScanner) scoringProfiles() *ProfileSet {
+    if profiles := s.profiles.Load(); profiles != nil {
+        return profiles
+    }
+    return DefaultProfiles()
+}
+
+// weight returns a factor weight of the profile, or false when it keeps the learned or default weight
+func (sp *ScoringProfile) weight(name string) (float64, bool) {
+    if sp == nil {
+        return 0, false
+    }
+    weight, ok := sp.Weights[name]
+    return weight, ok
+}
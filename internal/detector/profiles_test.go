@@ -0,0 +1,155 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"stackguard-task/internal/models"
+)
+
+func TestProfilesFileCompile(t *testing.T) {
+    value := func(v float64) *float64 { return &v }
+    tests := []struct {
+        name    string
+        file    ProfilesFile
+        wantErr []string // all expected in the error, none for a valid file
+    }{
+        {
+            name: "valid",
+            file: ProfilesFile{
+                Profiles: map[string]ProfileDefinition{
+                    "strict":  {MinConfidence: value(0.2), Weights: map[string]float64{"context": 0.5, "entropy": 0}},
+                    "lenient": {MinConfidence: value(0.6), AlertThreshold: value(0.9), EntropyThreshold: value(3.5)},
+                },
+                Channels: map[string]string{"prod-ops": "strict"},
+                Teams:    map[string]string{"training": "lenient"},
+                Rules:    map[string]string{"generic-api-key": DefaultProfileName},
+            },
+        },
+        {
+            name:    "unknown factor",
+            file:    ProfilesFile{Profiles: map[string]ProfileDefinition{"p": {Weights: map[string]float64{"colour": 1}}}},
+            wantErr: []string{`profile p: unknown factor "colour"`},
+        },
+        {
+            name:    "negative and all-zero weights",
+            file:    ProfilesFile{Profiles: map[string]ProfileDefinition{"p": {Weights: map[string]float64{"context": -1, "entropy": 0}}}},
+            wantErr: []string{"negative weight for context", "must not all be zero"},
+        },
+        {
+            name: "thresholds out of range",
+            file: ProfilesFile{Profiles: map[string]ProfileDefinition{
+                "p": {MinConfidence: value(1.5), EntropyThreshold: value(9), AlertThreshold: value(-0.1)},
+            }},
+            wantErr: []string{"minConfidence 1.50", "entropyThreshold 9.00", "alertThreshold -0.10"},
+        },
+        {
+            name:    "binding to an unknown profile",
+            file:    ProfilesFile{Channels: map[string]string{"prod-ops": "strict"}, Teams: map[string]string{"t1": "lenient"}},
+            wantErr: []string{`channel prod-ops: unknown profile "strict"`, `team t1: unknown profile "lenient"`},
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, err := tt.file.Compile()
+            if len(tt.wantErr) == 0 {
+                if err != nil {
+                    t.Errorf("unexpected error: %v", err)
+                }
+                return
+            }
+            if err == nil {
+                t.Fatal("invalid file accepted")
+            }
+            for _, want := range tt.wantErr {
+                if !strings.Contains(err.Error(), want) {
+                    t.Errorf("error %q does not mention %q", err, want)
+                }
+            }
+        })
+    }
+}
+
+func TestProfileSetResolve(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "profiles.yaml")
+    data := `profiles:
+  strict:  {minConfidence: 0.2}
+  lenient: {minConfidence: 0.6}
+  quiet:   {alertThreshold: 0.9}
+channels: {prod-ops: strict}
+teams:    {training: lenient}
+rules:    {generic-api-key: quiet}
+`
+    if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+        t.Fatal(err)
+    }
+    set, err := LoadProfiles(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name                    string
+        ruleID, channel, teamID string
+        want                    string
+    }{
+        {name: "channel beats team and rule", ruleID: "generic-api-key", channel: "prod-ops", teamID: "training", want: "strict"},
+        {name: "team beats rule", ruleID: "generic-api-key", channel: "general", teamID: "training", want: "lenient"},
+        {name: "rule", ruleID: "generic-api-key", channel: "general", want: "quiet"},
+        {name: "default", ruleID: "github-token", channel: "general", want: DefaultProfileName},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := set.Resolve(tt.ruleID, tt.channel, tt.teamID); got.Name != tt.want {
+                t.Errorf("Resolve = %s, want %s", got.Name, tt.want)
+            }
+        })
+    }
+
+    // Unset settings come from the built-in default
+    if quiet := set.Resolve("generic-api-key", "", ""); quiet.MinConfidence != defaultProfile.MinConfidence {
+        t.Errorf("quiet minConfidence = %v, want the default %v", quiet.MinConfidence, defaultProfile.MinConfidence)
+    }
+}
+
+func TestProfileAppliesToScan(t *testing.T) {
+    const text = "password: Xk29dLq8z!"
+    entropyThreshold, minConfidence := 7.0, 0.0
+    set, err := ProfilesFile{
+        Profiles: map[string]ProfileDefinition{
+            "picky": {EntropyThreshold: &entropyThreshold, MinConfidence: &minConfidence},
+        },
+        Channels: map[string]string{"picky-channel": "picky"},
+    }.Compile()
+    if err != nil {
+        t.Fatal(err)
+    }
+    scanner := NewSecretScanner()
+    scanner.SetProfiles(set)
+
+    scan := func(channelID string) float64 {
+        detections := scanner.ScanMessage(textMessageIn(channelID, text))
+        if len(detections) != 1 {
+            t.Fatalf("channel %s: reported %v", channelID, detectedTypes(detections))
+        }
+        if detections[0].Profile != set.Resolve(detections[0].RuleID, channelID, "").Name {
+            t.Errorf("channel %s: profile %q recorded", channelID, detections[0].Profile)
+        }
+        return detections[0].Confidence
+    }
+
+    // No value reaches 7 bits/char, so the picky profile halves the score
+    if general, picky := scan("general"), scan("picky-channel"); picky >= general {
+        t.Errorf("picky confidence %.2f, general %.2f", picky, general)
+    }
+}
+
+func textMessageIn(channelID, text string) models.TeamsMessage {
+    msg := textMessage("m1", text)
+    msg.ChannelID = channelID
+    return msg
+}
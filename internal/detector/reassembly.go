@@ -0,0 +1,250 @@
+package detector
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"stackguard-task/internal/models"
+)
+
+// MessageTail is the end of a recently scanned message. Callers keep the last
+// few per channel and user so that a secret split over consecutive messages
+// ("first half: ...", "second half: ...") can be reassembled.
+type MessageTail struct {
+    MessageID string
+    Text      string // normalized text, at most maxTailLength bytes
+    At        time.Time
+}
+
+// Only the end of a message can hold the first part of a split secret
+const maxTailLength = 256
+
+// Parts shorter than this are not worth gluing together
+const minFragmentLength = 4
+
+// Quotes and punctuation around a pasted part are not part of the secret
+const fragmentTrim = "\"'`.,;:!?()[]<>"
+
+// Plain words ("thanks", "Token") are never part of a split secret
+var plainWordPattern = regexp.MustCompile(`^[A-Z]?[a-z]+$`)
+
+// Tail returns the part of msg to keep for reassembling secrets split across messages
+func (s *SecretScanner) Tail(msg models.TeamsMessage) MessageTail {
+    text := s.preprocessContent(msg.Body).text
+    if len(text) > maxTailLength {
+        cut := len(text) - maxTailLength
+        for cut < len(text) && !utf8.RuneStart(text[cut]) {
+            cut++
+        }
+        text = text[cut:]
+    }
+
+    at := msg.CreatedAt
+    if at.IsZero() {
+        at = time.Now()
+    }
+    return MessageTail{MessageID: msg.ID, Text: text, At: at}
+}
+
+// ScanMessageWithRecent scans msg like ScanMessage and additionally reports
+// secrets that only appear when its last token is appended to the last tokens
+// of recent, the tails of the same user's preceding messages (oldest first)
+func (s *SecretScanner) ScanMessageWithRecent(msg models.TeamsMessage, recent []MessageTail) []models.SecretDetection {
+    detections := s.ScanMessage(msg)
+    if len(recent) == 0 {
+        return detections
+    }
+
+    split := s.scanSplit(recent, msg)
+    if len(split) == 0 {
+        return detections
+    }
+
+    // The reassembled secret replaces partial matches of the last part
+    detections = append(detections, split...)
+    sortByConfidence(detections)
+    return s.deduplicateDetections(detections)
+}
+
+// splitCandidate is a detection from one reassembly window with its span
+// measured from the end of the assembled text, which all windows share
+type splitCandidate struct {
+    detection models.SecretDetection
+    fromEnd   int // distance of the match start from the end
+    toEnd     int // distance of the match end from the end
+}
+
+// scanSplit tries every window of recent tails that ends with msg, longest
+// first, and keeps the best non-overlapping secrets spanning a message boundary
+func (s *SecretScanner) scanSplit(recent []MessageTail, msg models.TeamsMessage) []models.SecretDetection {
+    normalized := s.preprocessContent(msg.Body)
+    if start, end := fragmentSpan(normalized.text); !isSplitFragment(normalized.text[start:end]) {
+        return nil
+    }
+
+    // A message without a usable last token breaks the chain
+    first := len(recent)
+    for first > 0 {
+        text := recent[first-1].Text
+        if start, end := fragmentSpan(text); !isSplitFragment(text[start:end]) {
+            break
+        }
+        first--
+    }
+
+    var candidates []splitCandidate
+    for i := first; i < len(recent); i++ {
+        candidates = append(candidates, s.scanSplitWindow(recent[i:], msg, normalized)...)
+    }
+
+    sort.SliceStable(candidates, func(i, j int) bool {
+        return candidates[i].detection.Confidence > candidates[j].detection.Confidence
+    })
+    var kept []splitCandidate
+    var detections []models.SecretDetection
+    for _, candidate := range candidates {
+        overlapping := false
+        for _, other := range kept {
+            if candidate.toEnd < other.fromEnd && other.toEnd < candidate.fromEnd {
+                overlapping = true
+                break
+            }
+        }
+        if !overlapping {
+            kept = append(kept, candidate)
+            detections = append(detections, candidate.detection)
+        }
+    }
+    return detections
+}
+
+// scanSplitWindow scans the last tokens of window and msg glued together. The
+// first message keeps its text before the token so its keywords score the match.
+func (s *SecretScanner) scanSplitWindow(window []MessageTail, msg models.TeamsMessage, normalized normalizedText) []splitCandidate {
+    var sb strings.Builder
+    partEnds := make([]int, len(window))
+    for i, tail := range window {
+        start, end := fragmentSpan(tail.Text)
+        if i == 0 {
+            sb.WriteString(tail.Text[:start])
+        }
+        sb.WriteString(tail.Text[start:end])
+        partEnds[i] = sb.Len()
+    }
+    boundary := sb.Len()
+    lastStart, lastEnd := fragmentSpan(normalized.text)
+    sb.WriteString(normalized.text[lastStart:lastEnd])
+    assembled := sb.String()
+
+    // Earlier messages have no position in this body, their part of a match
+    // is located at the start of this message's token
+    toOriginal := func(start, end int) (int, int) {
+        start = max(start, boundary)
+        end = max(end, start+1)
+        return normalized.originalSpan(lastStart+start-boundary, lastStart+end-boundary)
+    }
+
+    pass := &scanPass{
+        scanner:        s,
+        rules:          s.rules.Load(),
+        msg:            msg,
+        normalized:     normalized,
+        parentText:     s.parentContext(msg),
+        confidenceCalc: s.newConfidenceCalculator(),
+        profiles:       s.scoringProfiles(),
+        suppression:    s.suppressionVocabulary(),
+        needsPartner:   make(map[string]bool),
+    }
+    pass.keywords = s.keywordDictionaries().Detect(assembled + "\n" + pass.parentText)
+    // Splitting a secret is deliberate evasion, like spacing it out
+    pass.scanText(assembled, toOriginal, textVariant{deobfuscated: true})
+
+    var candidates []splitCandidate
+    for _, detection := range pass.anchoredDetections() {
+        // The last occurrence is the one reaching into this message's token
+        at := strings.LastIndex(assembled, detection.FullValue)
+        end := at + len(detection.FullValue)
+        if at < 0 || at >= boundary || end <= boundary {
+            continue
+        }
+        // The earlier messages' part is a secret of its own, already reported
+        // with its message; the glued-on token merely extends the match
+        if pattern, ok := pass.rules.pattern(detection.RuleID); ok && pattern.matchesAt(assembled[:boundary], at) {
+            continue
+        }
+
+        var messageIDs []string
+        partStart := 0
+        for i, partEnd := range partEnds {
+            if at < partEnd && end > partStart {
+                messageIDs = append(messageIDs, window[i].MessageID)
+            }
+            partStart = partEnd
+        }
+        detection.MessageIDs = append(messageIDs, msg.ID)
+        detection.ID = generateDetectionID(strings.Join(detection.MessageIDs, "+"), detection.FullValue, detection.StartOffset)
+
+        candidates = append(candidates, splitCandidate{
+            detection: detection,
+            fromEnd:   len(assembled) - at,
+            toEnd:     len(assembled) - end,
+        })
+    }
+    return candidates
+}
+
+// matchesAt reports whether the rule finds a secret starting at offset in text
+func (sp SecretPattern) matchesAt(text string, offset int) bool {
+    for _, loc := range sp.Pattern.FindAllStringSubmatchIndex(text, -1) {
+        if start, _ := sp.secretSpan(loc); start == offset {
+            return true
+        }
+    }
+    return false
+}
+
+// Spent reports whether the tail's last token is part of a secret already
+// detected in its own message. Glued to a following message it would only
+// report that secret again, so the tail cannot start a split secret.
+func (t MessageTail) Spent(detections []models.SecretDetection) bool {
+    start, end := fragmentSpan(t.Text)
+    fragment := t.Text[start:end]
+    if fragment == "" {
+        return false
+    }
+    for _, detection := range detections {
+        if len(detection.MessageIDs) > 0 || detection.FullValue == "" {
+            continue
+        }
+        if strings.Contains(detection.FullValue, fragment) || strings.Contains(fragment, detection.FullValue) {
+            return true
+        }
+    }
+    return false
+}
+
+// fragmentSpan locates the last whitespace-separated token of text without
+// the quotes and punctuation around it
+func fragmentSpan(text string) (int, int) {
+    end := len(strings.TrimRightFunc(text, func(r rune) bool {
+        return unicode.IsSpace(r) || strings.ContainsRune(fragmentTrim, r)
+    }))
+
+    start := 0
+    if space := strings.LastIndexFunc(text[:end], unicode.IsSpace); space >= 0 {
+        _, size := utf8.DecodeRuneInString(text[space:])
+        start = space + size
+    }
+    for start < end && strings.IndexByte(fragmentTrim, text[start]) >= 0 {
+        start++
+    }
+    return start, end
+}
+
+func isSplitFragment(fragment string) bool {
+    return len(fragment) >= minFragmentLength && !plainWordPattern.MatchString(fragment)
+}
@@ -0,0 +1,136 @@
+package detector
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// RulesStatus describes the currently active rule pack and the outcome of the last reload
+type RulesStatus struct {
+    Version     string     `json:"version"`
+    Path        string     `json:"path,omitempty"`
+    RuleCount   int        `json:"ruleCount"`
+    LoadedAt    time.Time  `json:"loadedAt"`
+    LastError   string     `json:"lastError,omitempty"`
+    LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+// Healthy reports whether the last reload attempt succeeded
+func (rs RulesStatus) Healthy() bool {
+    return rs.LastError == ""
+}
+
+// swapRules compiles the pack and atomically replaces the active rule set.
+// Callers other than constructors must hold updateMutex. A failed reload stays
+// recorded: runtime edits do not fix the rule file.
+func (s *SecretScanner) swapRules(pack *RulePack) error {
+    patterns, err := pack.Compile()
+    if err != nil {
+        return err
+    }
+
+    s.rules.Store(&ruleSet{pack: pack, patterns: patterns})
+
+    s.statusMutex.Lock()
+    s.status.Version = pack.Version
+    s.status.RuleCount = len(patterns)
+    s.status.LoadedAt = time.Now()
+    s.statusMutex.Unlock()
+
+    return nil
+}
+
+// ReloadRules re-reads the rule pack from disk (or the built-in pack when no
+// file is configured). On failure the previous rules stay active and the
+// error is recorded in RulesStatus.
+func (s *SecretScanner) ReloadRules() error {
+    s.updateMutex.Lock()
+    defer s.updateMutex.Unlock()
+
+    var pack *RulePack
+    var err error
+
+    if s.rulesPath == "" {
+        pack = DefaultRulePack()
+    } else {
+        pack, err = LoadRulePack(s.rulesPath)
+    }
+
+    if err == nil {
+        err = s.swapRules(pack)
+    }
+
+    if err != nil {
+        s.recordReloadError(err)
+        log.Printf("Rule reload failed, keeping rule pack version %s: %v", s.RulesVersion(), err)
+        return err
+    }
+
+    s.statusMutex.Lock()
+    s.status.LastError = ""
+    s.status.LastErrorAt = nil
+    s.statusMutex.Unlock()
+
+    log.Printf("Rule pack reloaded: version %s (%d rules)", pack.Version, len(s.rules.Load().patterns))
+    return nil
+}
+
+// RulesStatus returns a snapshot of the active rule pack state
+func (s *SecretScanner) RulesStatus() RulesStatus {
+    s.statusMutex.RLock()
+    defer s.statusMutex.RUnlock()
+    return s.status
+}
+
+func (s *SecretScanner) recordReloadError(err error) {
+    s.statusMutex.Lock()
+    defer s.statusMutex.Unlock()
+    now := time.Now()
+    s.status.LastError = err.Error()
+    s.status.LastErrorAt = &now
+}
+
+// WatchRuleFile polls the rule pack file and reloads it whenever its
+// modification time or size changes. It returns when stop is closed.
+func (s *SecretScanner) WatchRuleFile(interval time.Duration, stop <-chan struct{}) {
+    if s.rulesPath == "" || interval <= 0 {
+        return
+    }
+
+    lastMod, lastSize := statRuleFile(s.rulesPath)
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-stop:
+            return
+        case <-ticker.C:
+            info, err := os.Stat(s.rulesPath)
+            if err != nil {
+                // Only report the first failure until the file shows up again
+                if !lastMod.IsZero() {
+                    s.recordReloadError(err)
+                    log.Printf("Rule file watch error: %v", err)
+                }
+                lastMod, lastSize = time.Time{}, 0
+                continue
+            }
+            if info.ModTime().Equal(lastMod) && info.Size() == lastSize {
+                continue
+            }
+            lastMod, lastSize = info.ModTime(), info.Size()
+            // Failures are logged and recorded in RulesStatus by ReloadRules
+            s.ReloadRules()
+        }
+    }
+}
+
+func statRuleFile(path string) (time.Time, int64) {
+    info, err := os.Stat(path)
+    if err != nil {
+        return time.Time{}, 0
+    }
+    return info.ModTime(), info.Size()
+}
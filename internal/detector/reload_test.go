@@ -0,0 +1,115 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const brokenRulePack = `
+version: "2.0.0"
+rules:
+  - id: internal-api-token
+    name: Internal API Token
+    pattern: 'itk_[A-Za-z0-9{32}'
+    severity: HIGH
+    specificity: 0.95
+`
+
+const itkToken = "itk token itk_Xq8Vt3Lm9Rw2Xk7Pb4NcHs6FzJ2dQa5Y"
+
+func writeRulePack(t *testing.T, path, data string) {
+    t.Helper()
+    if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestReloadRulesKeepsPreviousRules(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "rules.yaml")
+    writeRulePack(t, path, yamlRulePack)
+    scanner, err := LoadSecretScanner(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    writeRulePack(t, path, brokenRulePack)
+    if err := scanner.ReloadRules(); err == nil {
+        t.Fatal("broken rule pack reloaded")
+    }
+    status := scanner.RulesStatus()
+    if status.Healthy() || status.LastErrorAt == nil || status.Version != "1.0.0" {
+        t.Errorf("status after a failed reload = %+v", status)
+    }
+    if types := detectedTypes(scanText(scanner, itkToken)); !slices.Equal(types, []string{"Internal API Token"}) {
+        t.Errorf("previous rules not kept, reported %v", types)
+    }
+
+    // A runtime edit does not fix the file
+    err = scanner.AddRule(RuleDefinition{
+        ID: "internal-api-secret", Name: "Internal API Secret", Pattern: `its_[A-Za-z0-9]{40}`,
+        Severity: "HIGH", Specificity: 0.9,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    if scanner.RulesStatus().Healthy() {
+        t.Error("runtime edit cleared the reload error")
+    }
+
+    writeRulePack(t, path, strings.Replace(yamlRulePack, `"1.0.0"`, `"2.0.0"`, 1))
+    if err := scanner.ReloadRules(); err != nil {
+        t.Fatal(err)
+    }
+    if status := scanner.RulesStatus(); !status.Healthy() || status.LastErrorAt != nil || status.Version != "2.0.0" {
+        t.Errorf("status after a successful reload = %+v", status)
+    }
+}
+
+func TestWatchRuleFile(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "rules.yaml")
+    writeRulePack(t, path, yamlRulePack)
+    scanner, err := LoadSecretScanner(path)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    stop := make(chan struct{})
+    done := make(chan struct{})
+    go func() {
+        scanner.WatchRuleFile(5*time.Millisecond, stop)
+        close(done)
+    }()
+    defer func() {
+        close(stop)
+        <-done
+    }()
+
+    waitFor := func(what string, ok func(RulesStatus) bool) {
+        t.Helper()
+        deadline := time.Now().Add(2 * time.Second)
+        for !ok(scanner.RulesStatus()) {
+            if time.Now().After(deadline) {
+                t.Fatalf("timed out waiting for %s, status %+v", what, scanner.RulesStatus())
+            }
+            time.Sleep(5 * time.Millisecond)
+        }
+    }
+
+    // Let the watcher record the file as loaded before changing it
+    time.Sleep(50 * time.Millisecond)
+    writeRulePack(t, path, brokenRulePack)
+    waitFor("the reload error", func(status RulesStatus) bool { return !status.Healthy() })
+    if status := scanner.RulesStatus(); status.Version != "1.0.0" || !strings.Contains(status.LastError, "internal-api-token") {
+        t.Errorf("status after a broken file = %+v", status)
+    }
+    if types := detectedTypes(scanText(scanner, itkToken)); !slices.Equal(types, []string{"Internal API Token"}) {
+        t.Errorf("previous rules not kept, reported %v", types)
+    }
+
+    writeRulePack(t, path, strings.Replace(yamlRulePack, `"1.0.0"`, `"2.0.0"`, 1))
+    waitFor("the fixed file", func(status RulesStatus) bool { return status.Version == "2.0.0" && status.Healthy() })
+}
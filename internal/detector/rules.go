@@ -0,0 +1,308 @@
+package detector
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed rules/default.yaml
+var defaultRulePackYAML []byte
+
+// RulePack is a versioned set of detection rules loaded from YAML or TOML
+type RulePack struct {
+    Version string           `yaml:"version" toml:"version" json:"version"`
+    Rules   []RuleDefinition `yaml:"rules" toml:"rules" json:"rules"`
+}
+
+// RuleDefinition is the on-disk form of a SecretPattern
+type RuleDefinition struct {
+    ID          string   `yaml:"id" toml:"id" json:"id"`
+    Name        string   `yaml:"name" toml:"name" json:"name"`
+    Description string   `yaml:"description" toml:"description" json:"description"`
+    Pattern     string   `yaml:"pattern" toml:"pattern" json:"pattern"`
+    Severity    string   `yaml:"severity" toml:"severity" json:"severity"`
+    Specificity float64  `yaml:"specificity" toml:"specificity" json:"specificity"`
+    Length      []int    `yaml:"length,flow" toml:"length" json:"length,omitempty"`
+    Keywords    []string `yaml:"keywords,flow" toml:"keywords" json:"keywords,omitempty"`
+    Allowlist   []string `yaml:"allowlist" toml:"allowlist" json:"allowlist,omitempty"`
+    Validator   string   `yaml:"validator,omitempty" toml:"validator" json:"validator,omitempty"`
+    Mask        string   `yaml:"mask,omitempty" toml:"mask" json:"mask,omitempty"`
+    // RequireKeyword drops matches without one of Keywords nearby, unless a
+    // Pair partner was found in the same message
+    RequireKeyword bool         `yaml:"requireKeyword,omitempty" toml:"requireKeyword" json:"requireKeyword,omitempty"`
+    Pair           *RulePairing `yaml:"pair,omitempty" toml:"pair" json:"pair,omitempty"`
+    Disabled       bool         `yaml:"disabled,omitempty" toml:"disabled" json:"disabled"`
+}
+
+// RulePairing combines a match with a match of a partner rule in the same
+// message into one detection, e.g. an AWS secret key with its access key ID
+type RulePairing struct {
+    With     []string `yaml:"with,flow" toml:"with" json:"with"`
+    Name     string   `yaml:"name" toml:"name" json:"name"`
+    Severity string   `yaml:"severity" toml:"severity" json:"severity"`
+}
+
+// Masking modes a rule can choose
+const (
+    maskPartial = "partial" // keep a short prefix/suffix for identification (default)
+    maskFull    = "full"    // hide the whole value, for low-entropy secrets such as passwords
+)
+
+// secretGroupName is the capture group name that marks the secret within a match
+const secretGroupName = "secret"
+
+var validSeverities = map[string]bool{
+    "CRITICAL": true,
+    "HIGH":     true,
+    "MEDIUM":   true,
+    "LOW":      true,
+}
+
+// DefaultRulePack returns the built-in rule pack shipped with the binary
+func DefaultRulePack() *RulePack {
+    pack, err := ParseRulePack(defaultRulePackYAML)
+    if err != nil {
+        panic(fmt.Sprintf("built-in rule pack is invalid: %v", err))
+    }
+    return pack
+}
+
+// LoadRulePack reads and parses a rule pack file, as TOML when its name ends
+// in .toml and as YAML otherwise
+func LoadRulePack(path string) (*RulePack, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("reading rule pack %s: %w", path, err)
+    }
+
+    parse := ParseRulePack
+    if strings.EqualFold(filepath.Ext(path), ".toml") {
+        parse = ParseTOMLRulePack
+    }
+    pack, err := parse(data)
+    if err != nil {
+        return nil, fmt.Errorf("rule pack %s: %w", path, err)
+    }
+    return pack, nil
+}
+
+// ParseRulePack decodes a YAML rule pack (JSON is accepted too, being valid YAML)
+func ParseRulePack(data []byte) (*RulePack, error) {
+    var pack RulePack
+    if err := yaml.Unmarshal(data, &pack); err != nil {
+        return nil, fmt.Errorf("parsing rule pack: %w", err)
+    }
+    if _, err := pack.Compile(); err != nil {
+        return nil, err
+    }
+    return &pack, nil
+}
+
+// ParseTOMLRulePack decodes a TOML rule pack, with one [[rules]] table per rule
+func ParseTOMLRulePack(data []byte) (*RulePack, error) {
+    var pack RulePack
+    if err := toml.Unmarshal(data, &pack); err != nil {
+        return nil, fmt.Errorf("parsing rule pack: %w", err)
+    }
+    if _, err := pack.Compile(); err != nil {
+        return nil, err
+    }
+    return &pack, nil
+}
+
+// Clone returns a copy of the pack that can be modified without affecting the
+// original, down to each rule's slices and pairing
+func (rp *RulePack) Clone() *RulePack {
+    clone := &RulePack{
+        Version: rp.Version,
+        Rules:   make([]RuleDefinition, len(rp.Rules)),
+    }
+    for i, rule := range rp.Rules {
+        rule.Length = slices.Clone(rule.Length)
+        rule.Keywords = slices.Clone(rule.Keywords)
+        rule.Allowlist = slices.Clone(rule.Allowlist)
+        if rule.Pair != nil {
+            pair := *rule.Pair
+            pair.With = slices.Clone(pair.With)
+            rule.Pair = &pair
+        }
+        clone.Rules[i] = rule
+    }
+    return clone
+}
+
+// Compile validates every rule and turns the pack into scanner patterns.
+// All validation problems are reported together so a bad pack can be fixed in one pass.
+func (rp *RulePack) Compile() ([]SecretPattern, error) {
+    var errs []error
+
+    if strings.TrimSpace(rp.Version) == "" {
+        errs = append(errs, errors.New("missing version"))
+    }
+    if len(rp.Rules) == 0 {
+        errs = append(errs, errors.New("no rules defined"))
+    }
+
+    patterns := make([]SecretPattern, 0, len(rp.Rules))
+    seen := make(map[string]bool)
+
+    for i, rule := range rp.Rules {
+        if rule.ID != "" && seen[rule.ID] {
+            errs = append(errs, fmt.Errorf("rule #%d (%s): duplicate id", i+1, rule.label()))
+            continue
+        }
+        seen[rule.ID] = true
+
+        pattern, err := rule.compile()
+        if err != nil {
+            errs = append(errs, fmt.Errorf("rule #%d (%s): %w", i+1, rule.label(), err))
+            continue
+        }
+        // Disabled rules are still validated so re-enabling them cannot fail
+        if rule.Disabled {
+            continue
+        }
+        patterns = append(patterns, pattern)
+    }
+
+    // Pairings may only name rules that exist in the pack
+    for i, rule := range rp.Rules {
+        if rule.Pair == nil {
+            continue
+        }
+        for _, partner := range rule.Pair.With {
+            if !seen[partner] || partner == rule.ID {
+                errs = append(errs, fmt.Errorf("rule #%d (%s): pair partner %q is not another rule in the pack", i+1, rule.label(), partner))
+            }
+        }
+    }
+
+    if len(errs) > 0 {
+        return nil, errors.Join(errs...)
+    }
+    return patterns, nil
+}
+
+func (rd RuleDefinition) label() string {
+    if rd.ID != "" {
+        return rd.ID
+    }
+    if rd.Name != "" {
+        return rd.Name
+    }
+    return "unnamed"
+}
+
+func (rd RuleDefinition) compile() (SecretPattern, error) {
+    var errs []string
+
+    if strings.TrimSpace(rd.ID) == "" {
+        errs = append(errs, "missing id")
+    }
+    if strings.TrimSpace(rd.Name) == "" {
+        errs = append(errs, "missing name")
+    }
+    if !validSeverities[rd.Severity] {
+        errs = append(errs, fmt.Sprintf("invalid severity %q (must be CRITICAL, HIGH, MEDIUM or LOW)", rd.Severity))
+    }
+    if rd.Specificity < 0 || rd.Specificity > 1 {
+        errs = append(errs, fmt.Sprintf("specificity %.2f out of range 0.0 - 1.0", rd.Specificity))
+    }
+
+    var minLength, maxLength int
+    switch len(rd.Length) {
+    case 0:
+    case 2:
+        minLength, maxLength = rd.Length[0], rd.Length[1]
+        if minLength < 0 || maxLength < minLength {
+            errs = append(errs, fmt.Sprintf("invalid length range [%d, %d]", minLength, maxLength))
+        }
+    default:
+        errs = append(errs, "length must be a [min, max] pair")
+    }
+
+    var compiled *regexp.Regexp
+    if rd.Pattern == "" {
+        errs = append(errs, "missing pattern")
+    } else {
+        var err error
+        if compiled, err = regexp.Compile(rd.Pattern); err != nil {
+            errs = append(errs, fmt.Sprintf("invalid regex: %v", err))
+        }
+    }
+
+    validator := tokenValidators[rd.Validator]
+    if rd.Validator != "" && validator == nil {
+        errs = append(errs, fmt.Sprintf("unknown validator %q", rd.Validator))
+    }
+
+    if rd.Mask != "" && rd.Mask != maskPartial && rd.Mask != maskFull {
+        errs = append(errs, fmt.Sprintf("invalid mask %q (must be partial or full)", rd.Mask))
+    }
+
+    if rd.Pair != nil {
+        if len(rd.Pair.With) == 0 || strings.TrimSpace(rd.Pair.Name) == "" {
+            errs = append(errs, "pair needs a partner list (with) and a name")
+        }
+        if !validSeverities[rd.Pair.Severity] {
+            errs = append(errs, fmt.Sprintf("invalid pair severity %q (must be CRITICAL, HIGH, MEDIUM or LOW)", rd.Pair.Severity))
+        }
+    }
+    if rd.RequireKeyword && len(rd.Keywords) == 0 {
+        errs = append(errs, "requireKeyword needs keywords")
+    }
+
+    var allowlist []*regexp.Regexp
+    for _, allow := range rd.Allowlist {
+        re, err := regexp.Compile(allow)
+        if err != nil {
+            errs = append(errs, fmt.Sprintf("invalid allowlist regex %q: %v", allow, err))
+            continue
+        }
+        allowlist = append(allowlist, re)
+    }
+
+    if len(errs) > 0 {
+        return SecretPattern{}, errors.New(strings.Join(errs, "; "))
+    }
+
+    // Groups named "secret" narrow the reported value to part of the match
+    var secretGroups []int
+    for i, name := range compiled.SubexpNames() {
+        if name == secretGroupName {
+            secretGroups = append(secretGroups, i)
+        }
+    }
+
+    keywords := make([]string, 0, len(rd.Keywords))
+    for _, keyword := range rd.Keywords {
+        keywords = append(keywords, strings.ToLower(keyword))
+    }
+
+    return SecretPattern{
+        ID:             rd.ID,
+        Name:           rd.Name,
+        Pattern:        compiled,
+        Severity:       rd.Severity,
+        Description:    rd.Description,
+        Specificity:    rd.Specificity,
+        MinLength:      minLength,
+        MaxLength:      maxLength,
+        Keywords:       keywords,
+        Allowlist:      allowlist,
+        Validator:      validator,
+        SecretGroups:   secretGroups,
+        MaskFull:       rd.Mask == maskFull,
+        RequireKeyword: rd.RequireKeyword,
+        Pair:           rd.Pair,
+    }, nil
+}
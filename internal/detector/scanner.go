@@ -3,125 +3,396 @@ package detector
 import (
 	"crypto/md5"
 	"fmt"
-	"html"
 	"regexp"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"stackguard-task/internal/models"
 )
 
 type SecretScanner struct {
+    // rules is swapped atomically on reload so in-flight scans keep the set they started with
+    rules       atomic.Pointer[ruleSet]
+    rulesPath   string
+    updateMutex sync.Mutex // serializes reloads and runtime rule edits
+
+    statusMutex sync.RWMutex
+    status      RulesStatus
+
+    // entropyScan enables the opt-in "High Entropy String" discovery pass
+    entropyScan atomic.Bool
+
+    // calibration is scoring learned from analyst feedback, nil for the defaults
+    calibration atomic.Pointer[Calibration]
+
+    // profiles tune scoring per rule, channel and team, nil for the built-in default
+    profiles atomic.Pointer[ProfileSet]
+
+    // dictionaries translate context keywords, nil for the built-in languages
+    dictionaries atomic.Pointer[KeywordDictionaries]
+
+    // suppression discards test and placeholder values, nil for the built-in vocabulary
+    suppression  atomic.Pointer[SuppressionVocabulary]
+    suppressions suppressionLog // audit trail of discarded matches
+}
+
+// ruleSet is an immutable compiled rule pack
+type ruleSet struct {
+    pack     *RulePack
     patterns []SecretPattern
 }
 
+// pattern looks up an active pattern by rule ID
+func (rs *ruleSet) pattern(id string) (*SecretPattern, bool) {
+    for i := range rs.patterns {
+        if rs.patterns[i].ID == id {
+            return &rs.patterns[i], true
+        }
+    }
+    return nil, false
+}
+
 type SecretPattern struct {
+    ID          string
     Name        string
     Pattern     *regexp.Regexp
     Severity    string
     Confidence  float64
     Description string
+    Specificity float64
+    MinLength   int
+    MaxLength   int
+    Keywords    []string
+    Allowlist   []*regexp.Regexp
+    Validator   tokenValidator // optional offline structural check
+    // SecretGroups are the indexes of capture groups named "secret"; when one
+    // participates in a match only that part is reported (e.g. the password
+    // after "password is")
+    SecretGroups   []int
+    MaskFull       bool // hide the whole value instead of keeping a prefix/suffix
+    RequireKeyword bool // only report matches with one of Keywords in the context
+    Pair           *RulePairing
+}
+
+// secretSpan narrows a submatch index slice to the reported secret
+func (sp SecretPattern) secretSpan(loc []int) (int, int) {
+    for _, group := range sp.SecretGroups {
+        if loc[2*group] >= 0 {
+            return loc[2*group], loc[2*group+1]
+        }
+    }
+    return loc[0], loc[1]
+}
+
+// mask redacts a value according to the rule's masking mode
+func (sp SecretPattern) mask(secret string) string {
+    if sp.MaskFull {
+        return maskFullSecret(secret)
+    }
+    return maskSecret(secret)
 }
 
+// NewSecretScanner creates a scanner using the built-in default rule pack
 func NewSecretScanner() *SecretScanner {
-    return &SecretScanner{
-        patterns: getSecretPatterns(),
+    scanner, err := NewSecretScannerFromPack(DefaultRulePack())
+    if err != nil {
+        panic(err)
     }
+    return scanner
+}
+
+// NewSecretScannerFromPack creates a scanner from an already parsed rule pack
+func NewSecretScannerFromPack(pack *RulePack) (*SecretScanner, error) {
+    scanner := &SecretScanner{}
+    if err := scanner.swapRules(pack); err != nil {
+        return nil, err
+    }
+    return scanner, nil
+}
+
+// LoadSecretScanner creates a scanner from the rule pack at path, or from the
+// default pack when path is empty
+func LoadSecretScanner(path string) (*SecretScanner, error) {
+    if path == "" {
+        return NewSecretScanner(), nil
+    }
+    pack, err := LoadRulePack(path)
+    if err != nil {
+        return nil, err
+    }
+    scanner, err := NewSecretScannerFromPack(pack)
+    if err != nil {
+        return nil, err
+    }
+    scanner.rulesPath = path
+    scanner.status.Path = path
+    return scanner, nil
+}
+
+// SetEntropyScan turns entropy-only discovery of unknown token formats on or off
+func (s *SecretScanner) SetEntropyScan(enabled bool) {
+    s.entropyScan.Store(enabled)
+}
+
+// RulesVersion returns the version of the rule pack currently in use
+func (s *SecretScanner) RulesVersion() string {
+    return s.rules.Load().pack.Version
 }
 
 func (s *SecretScanner) ScanMessage(msg models.TeamsMessage) []models.SecretDetection {
-    var detections []models.SecretDetection
-    content := msg.Body.Content
+    // Pin the rule set for the whole scan, a concurrent reload only affects later scans.
+    // Preprocess content to handle newlines and special characters,
+    // keeping a map back to the original body for offsets
+    pass := &scanPass{
+        scanner:        s,
+        rules:          s.rules.Load(),
+        msg:            msg,
+        normalized:     s.preprocessContent(msg.Body),
+        parentText:     s.parentContext(msg),
+        confidenceCalc: s.newConfidenceCalculator(),
+        profiles:       s.scoringProfiles(),
+        suppression:    s.suppressionVocabulary(),
+        needsPartner:   make(map[string]bool),
+        suppressed:     make(map[string]models.SuppressedCandidate),
+    }
+    content := pass.normalized.text
+    pass.keywords = s.keywordDictionaries().Detect(content + "\n" + pass.parentText)
     
-    // Preprocess content to handle newlines and special characters
-    content = s.preprocessContent(content)
+    pass.scanText(content, pass.normalized.originalSpan, textVariant{})
     
-    // Handle large messages (>5000 chars) by scanning in overlapping chunks
-    originalContent := content
-    confidenceCalc := NewConfidenceCalculator()
+    // Secrets pasted with a space every few characters
+    pass.scanDespaced(content)
+    
+    // Look inside base64, hex and URL-encoded blobs as well
+    pass.scanEncoded(content, pass.normalized.originalSpan, textVariant{})
+    
+    // Random-looking tokens near credential keywords that no rule recognized
+    if s.entropyScan.Load() {
+        pass.scanEntropy(content)
+    }
+    
+    // Whole pasted config files (.env, kubeconfig, ...) become one grouped detection
+    pass.scanConfigBlobs(content)
+    
+    // Keyword-less matches (e.g. a bare AWS secret key) need their partner in the message
+    detections := pass.anchoredDetections()
+    
+    // Sort detections by confidence (highest first)
+    sortByConfidence(detections)
+    
+    // Deduplicate overlapping detections - keep only the highest confidence one
+    detections = s.deduplicateDetections(detections)
+    
+    // Merge paired credentials (AWS access key + secret key) into one finding
+    detections = pass.pairDetections(detections)
+    sortByConfidence(detections)
+    
+    pass.flushSuppressions()
+    
+    return detections
+}
+
+// textVariant describes how scanned text was derived from the message
+type textVariant struct {
+    decodedVia   []string // decoders applied, outermost first
+    deobfuscated bool     // spacing was removed to form the text
+}
+
+// spanMapper maps a [start, end) range of scanned text to the original message body
+type spanMapper func(start, end int) (int, int)
+
+// scanPass holds the state of a single ScanMessage call
+type scanPass struct {
+    scanner        *SecretScanner
+    rules          *ruleSet
+    msg            models.TeamsMessage
+    normalized     normalizedText
+    parentText     string // normalized text of the replied-to message, if known
+    confidenceCalc *ConfidenceCalculator
+    profiles       *ProfileSet
+    keywords       *KeywordSet // context keywords for the languages of the message and its parent
+    suppression    *SuppressionVocabulary
+    detections     []models.SecretDetection
+    needsPartner   map[string]bool // detections (by partnerKey) only kept if a pair partner is found
+    // suppressed collects discarded matches for the audit trail, nil when not recorded
+    suppressed map[string]models.SuppressedCandidate
+}
 
+// scanText runs every pattern over content, which is the message text itself
+// or a variant of it derived by decoding or de-obfuscation
+func (p *scanPass) scanText(content string, toOriginal spanMapper, variant textVariant) {
+    // Handle large messages (>5000 chars) by scanning in overlapping chunks
     // Chunking parameters chosen to balance speed and boundary accuracy
     const maxScanWindow = 5000
     const chunkSize = 4096
     const overlap = 512 // Make sure secrets spread across chunks are caught
 
-    scanChunk := func(chunk string) {
-        for _, pattern := range s.patterns {
-            matches := pattern.Pattern.FindAllString(chunk, -1)
-            for _, match := range matches {
-                context := extractContext(originalContent, match)
-                if s.isFalsePositive(match, context, pattern.Name) {
-                    continue
-                }
-                confidence := confidenceCalc.CalculateConfidence(match, context, pattern.Name)
-                if confidence < 0.3 {
+    if len(content) <= maxScanWindow {
+        p.scanChunk(content, content, 0, toOriginal, variant)
+        return
+    }
+
+    // Sliding window with overlap
+    step := chunkSize - overlap
+    if step <= 0 {
+        step = chunkSize
+    }
+    for start := 0; start < len(content); start += step {
+        end := start + chunkSize
+        if end > len(content) {
+            end = len(content)
+        }
+        p.scanChunk(content, content[start:end], start, toOriginal, variant)
+        if end == len(content) {
+            break
+        }
+    }
+}
+
+func (p *scanPass) scanChunk(content, chunk string, chunkOffset int, toOriginal spanMapper, variant textVariant) {
+    msg := p.msg
+    for _, pattern := range p.rules.patterns {
+        matches := pattern.Pattern.FindAllStringSubmatchIndex(chunk, -1)
+        for _, loc := range matches {
+            secretStart, secretEnd := pattern.secretSpan(loc)
+            match := chunk[secretStart:secretEnd]
+            matchStart, matchEnd := chunkOffset+secretStart, chunkOffset+secretEnd
+            masked := pattern.mask(match)
+            context := extractContext(content, matchStart, matchEnd, masked)
+            startOffset, endOffset := toOriginal(matchStart, matchEnd)
+            if rule, trigger, ok := p.scanner.isFalsePositive(match, context, pattern, p.suppression, p.keywords); ok {
+                p.suppress(pattern, match, context, startOffset, rule, trigger, 0)
+                continue
+            }
+            // Without keywords a pairable match can still be anchored by its partner.
+            // Keywords in the replied-to message count ("which AWS secret key?").
+            needsPartner := pattern.RequireKeyword && !p.keywords.containsAny(context, pattern.Keywords) &&
+                !p.keywords.containsAny(p.parentText, pattern.Keywords)
+            if needsPartner && pattern.Pair == nil {
+                p.suppress(pattern, match, context, startOffset, SuppressedByNoKeyword, "", 0)
+                continue
+            }
+            traits := p.normalized.traitsAt(startOffset, endOffset)
+            traits.Deobfuscated = traits.Deobfuscated || variant.deobfuscated
+            traits.ParentContext = p.parentText
+            traits.ChannelID = msg.ChannelID
+            traits.Profile = p.profiles.Resolve(pattern.ID, msg.ChannelID, msg.TeamID)
+            traits.Keywords = p.keywords
+            if pattern.Validator != nil {
+                validation := pattern.Validator(match)
+                if validation.Veto {
+                    p.suppress(pattern, match, context, startOffset, SuppressedByValidator, validation.Reason, 0)
                     continue
                 }
-                detection := models.SecretDetection{
-                    ID:          generateDetectionID(msg.ID, match),
-                    MessageID:   msg.ID,
-                    ChannelID:   msg.ChannelID,
-                    TeamID:      msg.TeamID,
-                    UserID:      msg.From.User.ID,
-                    UserName:    msg.From.User.DisplayName,
-                    SecretType:  pattern.Name,
-                    MaskedValue: maskSecret(match),
-                    FullValue:   match,
-                    Confidence:  confidence,
-                    Context:     context,
-                    DetectedAt:  time.Now(),
-                    Severity:    pattern.Severity,
-                    Status:      "new",
-                }
-                detections = append(detections, detection)
+                traits.Validation = &validation
+            }
+            explanation := p.confidenceCalc.ExplainConfidence(match, context, pattern, traits)
+            confidence := explanation.Confidence
+            if confidence < traits.Profile.MinConfidence {
+                p.suppress(pattern, match, context, startOffset, SuppressedByConfidence,
+                    fmt.Sprintf("%s profile minimum %.2f", traits.Profile.Name, traits.Profile.MinConfidence), confidence)
+                continue
+            }
+            line, column := p.normalized.lineColumn(startOffset)
+            severity := pattern.Severity
+            if traits.Deobfuscated {
+                // Deliberate evasion makes the leak higher risk
+                severity = escalateSeverity(severity)
+            }
+            detection := models.SecretDetection{
+                ID:           generateDetectionID(msg.ID, match, startOffset),
+                MessageID:    msg.ID,
+                ChannelID:    msg.ChannelID,
+                TeamID:       msg.TeamID,
+                ThreadID:     msg.ThreadID,
+                ReplyToID:    msg.ReplyToID,
+                UserID:       msg.From.User.ID,
+                UserName:     msg.From.User.DisplayName,
+                SecretType:   pattern.Name,
+                RuleID:       pattern.ID,
+                MaskedValue:  masked,
+                FullValue:    match,
+                Confidence:   confidence,
+                Context:      context,
+                DetectedAt:   time.Now(),
+                Severity:     severity,
+                Status:       "new",
+                StartOffset:  startOffset,
+                EndOffset:    endOffset,
+                Line:         line,
+                Column:       column,
+                InCodeBlock:  traits.InCodeBlock,
+                InLinkHref:   traits.InLinkHref,
+                DecodedVia:   variant.decodedVia,
+                Deobfuscated: traits.Deobfuscated,
+                Profile:      traits.Profile.Name,
+                Explanation:  &explanation,
+            }
+            if traits.Validation != nil {
+                detection.Validated = traits.Validation.Valid
+                detection.ValidationReason = traits.Validation.Reason
             }
+            if needsPartner {
+                p.needsPartner[partnerKey(detection)] = true
+            }
+            p.detections = append(p.detections, detection)
         }
     }
+}
 
-    if len(content) <= maxScanWindow {
-        scanChunk(content)
+func (s *SecretScanner) preprocessContent(body models.MessageBody) normalizedText {
+    // Teams delivers rich messages as HTML, strip markup before matching
+    var text normalizedText
+    if strings.EqualFold(body.ContentType, "html") {
+        text = extractTeamsHTML(body.Content)
     } else {
-        // Sliding window with overlap
-        step := chunkSize - overlap
-        if step <= 0 {
-            step = chunkSize
-        }
-        for start := 0; start < len(content); start += step {
-            end := start + chunkSize
-            if end > len(content) {
-                end = len(content)
-            }
-            scanChunk(content[start:end])
-            if end == len(content) {
-                break
-            }
-        }
+        text = decodeEscapes(body.Content)
     }
     
-    // Sort detections by confidence (highest first)
-    sort.Slice(detections, func(i, j int) bool {
-        return detections[i].Confidence > detections[j].Confidence
-    })
-    
-    // Deduplicate overlapping detections - keep only the highest confidence one
-    detections = s.deduplicateDetections(detections)
-    
-    return detections
+    // Undo zero-width and look-alike character tricks before whitespace handling
+    return normalizeWhitespace(foldConfusables(text))
 }
 
-func (s *SecretScanner) preprocessContent(content string) string {
-    // Decode HTML entities (Teams messages might contain &lt;, &gt;, etc.)
-    content = html.UnescapeString(content)
-    
-    // Convert JSON-escaped newlines to actual newlines
-    content = strings.ReplaceAll(content, "\\n", "\n")
-    content = strings.ReplaceAll(content, "\\r", "\r")
-    content = strings.ReplaceAll(content, "\\t", "\t")
-    
-    // Normalize different types of whitespace to spaces for pattern matching
-    // This helps catch secrets that might be split with unusual whitespace
-    return regexp.MustCompile(`\s+`).ReplaceAllString(content, " ")
+// Only the start of a long parent message is used as context
+const maxParentContext = 500
+
+// parentContext returns the normalized text of the message msg replies to
+func (s *SecretScanner) parentContext(msg models.TeamsMessage) string {
+    if msg.ParentBody == nil {
+        return ""
+    }
+    text := s.preprocessContent(*msg.ParentBody).text
+    if len(text) > maxParentContext {
+        cut := maxParentContext
+        for cut > 0 && !utf8.RuneStart(text[cut]) {
+            cut--
+        }
+        text = text[:cut]
+    }
+    return text
+}
+
+// escalateSeverity raises a severity by one level
+func escalateSeverity(severity string) string {
+    switch severity {
+    case "LOW":
+        return "MEDIUM"
+    case "MEDIUM":
+        return "HIGH"
+    default:
+        return "CRITICAL"
+    }
+}
+
+// sortByConfidence orders detections highest confidence first
+func sortByConfidence(detections []models.SecretDetection) {
+    sort.SliceStable(detections, func(i, j int) bool {
+        return detections[i].Confidence > detections[j].Confidence
+    })
 }
 
 func (s *SecretScanner) deduplicateDetections(detections []models.SecretDetection) []models.SecretDetection {
@@ -167,149 +438,58 @@ func (s *SecretScanner) deduplicateDetections(detections []models.SecretDetectio
     return deduplicated
 }
 
-// detectionsOverlap checks if two detections are for the same or overlapping secrets
+// detectionsOverlap checks if two detections cover overlapping regions of the message
 func (s *SecretScanner) detectionsOverlap(d1, d2 models.SecretDetection) bool {
-    // Same exact match
-    if d1.FullValue == d2.FullValue {
-        return true
+    if d1.StartOffset >= d2.EndOffset || d2.StartOffset >= d1.EndOffset {
+        return false
     }
     
-    // One secret contains the other (e.g., private key contains base64 chunks)
-    if strings.Contains(d1.FullValue, d2.FullValue) || strings.Contains(d2.FullValue, d1.FullValue) {
-        return true
+    // Secrets decoded from the same blob share its span, so only treat them as
+    // duplicates when one value contains the other
+    if len(d1.DecodedVia) > 0 && len(d2.DecodedVia) > 0 {
+        return strings.Contains(d1.FullValue, d2.FullValue) || strings.Contains(d2.FullValue, d1.FullValue)
     }
     
-    // Check if they have significant overlap (>80% of the shorter string)
-    shorter, longer := d1.FullValue, d2.FullValue
-    if len(d2.FullValue) < len(d1.FullValue) {
-        shorter, longer = d2.FullValue, d1.FullValue
-    }
-    
-    // Only check overlap for reasonably long secrets
-    if len(shorter) > 10 {
-        overlapThreshold := int(float64(len(shorter)) * 0.8)
-        if strings.Contains(longer, shorter[:overlapThreshold]) {
-            return true
-        }
-    }
-    
-    return false
+    return true
 }
 
-func (s *SecretScanner) isFalsePositive(match, context, secretType string) bool {
-    lowerContext := strings.ToLower(context)
-    lowerMatch := strings.ToLower(match)
-    
-    // Common false positive patterns
-    falsePositives := []string{
-        "test", "example", "demo", "sample", "placeholder",
-        "fake", "mock", "dummy", "template", "documentation",
-        "akiaxxxxxxxxtest", "akiaiosfodnn7example", "your-api-key",
-        "replace-with", "insert-your", "add-your",
-    }
-    
-    // Highly specific secret types should be harder to reject as false positives
-    isHighlySpecific := secretType == "Private Key" || secretType == "GitHub Token" || secretType == "AWS Access Key" || secretType == "Google API Key"
-
-    for _, fp := range falsePositives {
-        if strings.Contains(lowerMatch, fp) {
-            return true
-        }
-        if strings.Contains(lowerContext, fp) {
-            if isHighlySpecific {
-                // Do not auto-reject highly specific matches just due to nearby context
-                continue
-            }
-            return true
+// isFalsePositive reports whether a match is a test, example or placeholder
+// value, with the suppression rule and the entry that fired it
+func (s *SecretScanner) isFalsePositive(match, context string, pattern SecretPattern, vocabulary *SuppressionVocabulary, keywords *KeywordSet) (string, string, bool) {
+    // Rule-level allowlist entries always win
+    for _, allow := range pattern.Allowlist {
+        if allow.MatchString(match) {
+            return SuppressedByAllowlist, allow.String(), true
         }
     }
     
-    return false
+    return vocabulary.suppress(match, context, pattern, keywords)
 }
 
-func extractContext(content, match string) string {
-    index := strings.Index(content, match)
-    if index == -1 {
+// extractContext returns the text around content[start:end] with that occurrence replaced by masked
+func extractContext(content string, start, end int, masked string) string {
+    if start < 0 || end > len(content) || start >= end {
         return ""
     }
     
-    start := index - 50
-    if start < 0 {
-        start = 0
+    contextStart := start - 50
+    if contextStart < 0 {
+        contextStart = 0
     }
     
-    end := index + len(match) + 50
-    if end > len(content) {
-        end = len(content)
+    contextEnd := end + 50
+    if contextEnd > len(content) {
+        contextEnd = len(content)
     }
     
-    context := content[start:end]
     // Replace the actual secret with masked version in context
-    context = strings.ReplaceAll(context, match, maskSecret(match))
+    context := content[contextStart:start] + masked + content[end:contextEnd]
     
     return strings.TrimSpace(context)
 }
 
-func generateDetectionID(messageID, secret string) string {
-    hash := md5.Sum([]byte(messageID + secret))
+// generateDetectionID derives a stable ID from the message, the secret and its position
+func generateDetectionID(messageID, secret string, offset int) string {
+    hash := md5.Sum([]byte(fmt.Sprintf("%s:%d:%s", messageID, offset, secret)))
     return fmt.Sprintf("det_%x", hash)[:16]
 }
-
-func getSecretPatterns() []SecretPattern {
-    return []SecretPattern{
-        {
-            Name:        "AWS Access Key",
-            Pattern:     regexp.MustCompile(`AKIA[0-9A-Z]{16}`),
-            Severity:    "HIGH",
-            Description: "AWS Access Key ID detected",
-        },
-        {
-            Name:        "AWS Secret Key",
-            Pattern:     regexp.MustCompile(`[A-Za-z0-9/+=]{40}`),
-            Severity:    "HIGH",
-            Description: "Potential AWS Secret Access Key",
-        },
-        {
-            Name:        "GitHub Token",
-            Pattern:     regexp.MustCompile(`ghp_[A-Za-z0-9]{36}`),
-            Severity:    "HIGH",
-            Description: "GitHub Personal Access Token",
-        },
-        {
-            Name:        "JWT Token",
-            Pattern:     regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`),
-            Severity:    "MEDIUM",
-            Description: "JSON Web Token detected",
-        },
-        {
-            Name:        "API Key Generic",
-            Pattern:     regexp.MustCompile(`(?i)(api[_-]?key|apikey|secret[_-]?key)["\s]*[:=]["\s]*[A-Za-z0-9]{20,}`),
-            Severity:    "MEDIUM",
-            Description: "Generic API key pattern",
-        },
-        {
-            Name:        "Database URL",
-            Pattern:     regexp.MustCompile(`(?i)(mongodb|mysql|postgres|redis)://[^\s]+`),
-            Severity:    "HIGH",
-            Description: "Database connection string",
-        },
-        {
-            Name:        "Private Key",
-            Pattern:     regexp.MustCompile(`-----BEGIN [A-Z ]+PRIVATE KEY-----[\s\S]*?-----END [A-Z ]+PRIVATE KEY-----`),
-            Severity:    "CRITICAL",
-            Description: "Private key detected",
-        },
-        {
-            Name:        "Slack Token",
-            Pattern:     regexp.MustCompile(`xox[baprs]-[A-Za-z0-9-]+`),
-            Severity:    "HIGH",
-            Description: "Slack API token",
-        },
-        {
-            Name:        "Google API Key",
-            Pattern:     regexp.MustCompile(`AIza[0-9A-Za-z\\-_]{35}`),
-            Severity:    "HIGH",
-            Description: "Google API key",
-        },
-    }
-}
\ No newline at end of file
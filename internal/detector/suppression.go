@@ -0,0 +1,241 @@
+package detector
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"stackguard-task/internal/models"
+)
+
+//go:embed suppression/default.yaml
+var defaultSuppressionYAML []byte
+
+// SuppressionVocabulary is the words and phrases that mark a match as a test,
+// example or placeholder value, see suppression/default.yaml for the format
+type SuppressionVocabulary struct {
+    Words   []string `yaml:"words" json:"words"`     // in the value, or whole words in the context
+    Values  []string `yaml:"values" json:"values"`   // in the value only
+    Phrases []string `yaml:"phrases" json:"phrases"` // whole words in the context
+}
+
+// Suppression rules, recorded with every discarded match
+const (
+    SuppressedByAllowlist  = "allowlist"             // the rule's allowlist matched the value
+    SuppressedByValue      = "word in value"         // a vocabulary word or value is part of the value
+    SuppressedByWord       = "word in context"       // a vocabulary word is near the value
+    SuppressedByPhrase     = "phrase in context"     // a vocabulary phrase is near the value
+    SuppressedByValidator  = "rejected by validator" // the rule's structural check vetoed the value
+    SuppressedByConfidence = "below min confidence"  // scored under the profile's minimum
+    SuppressedByNoKeyword  = "no keyword or partner" // a requireKeyword rule with neither nearby
+)
+
+// maxSuppressionLog bounds the audit trail, older entries are dropped first
+const maxSuppressionLog = 1000
+
+// DefaultSuppressionVocabulary returns the vocabulary shipped with the binary
+func DefaultSuppressionVocabulary() *SuppressionVocabulary {
+    vocabulary, err := parseSuppressionVocabulary(defaultSuppressionYAML)
+    if err != nil {
+        panic(fmt.Sprintf("built-in suppression vocabulary is invalid: %v", err))
+    }
+    return vocabulary
+}
+
+// LoadSuppressionVocabulary reads a vocabulary that replaces the built-in
+// one, or returns the built-in one when path is empty
+func LoadSuppressionVocabulary(path string) (*SuppressionVocabulary, error) {
+    if path == "" {
+        return DefaultSuppressionVocabulary(), nil
+    }
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("reading suppression vocabulary %s: %w", path, err)
+    }
+    vocabulary, err := parseSuppressionVocabulary(data)
+    if err != nil {
+        return nil, fmt.Errorf("suppression vocabulary %s: %w", path, err)
+    }
+    return vocabulary, nil
+}
+
+// parseSuppressionVocabulary lowercases entries and collapses the spacing of phrases
+func parseSuppressionVocabulary(data []byte) (*SuppressionVocabulary, error) {
+    var vocabulary SuppressionVocabulary
+    if err := yaml.Unmarshal(data, &vocabulary); err != nil {
+        return nil, fmt.Errorf("parsing: %w", err)
+    }
+
+    clean := func(entries []string) []string {
+        cleaned := make([]string, 0, len(entries))
+        for _, entry := range entries {
+            if entry = strings.Join(strings.Fields(strings.ToLower(entry)), " "); entry != "" {
+                cleaned = append(cleaned, entry)
+            }
+        }
+        return cleaned
+    }
+    vocabulary.Words = clean(vocabulary.Words)
+    vocabulary.Values = clean(vocabulary.Values)
+    vocabulary.Phrases = clean(vocabulary.Phrases)
+
+    if len(vocabulary.Words)+len(vocabulary.Values)+len(vocabulary.Phrases) == 0 {
+        return nil, errors.New("no words, values or phrases")
+    }
+    return &vocabulary, nil
+}
+
+// suppress returns the vocabulary rule that discards a match and the entry
+// that fired it. Highly specific formats are not discarded for their context.
+func (sv *SuppressionVocabulary) suppress(match, context string, pattern SecretPattern, keywords *KeywordSet) (string, string, bool) {
+    // Secrets have no word boundaries, so entries may sit anywhere in the value
+    lowerValue := strings.ToLower(credentialPart(match))
+    for _, entries := range [][]string{sv.Words, sv.Values} {
+        for _, entry := range entries {
+            if found, ok := keywords.find(lowerValue, entry); ok {
+                return SuppressedByValue, found, true
+            }
+        }
+    }
+
+    if pattern.Specificity >= 0.9 {
+        return "", "", false
+    }
+
+    lowerContext := strings.Join(strings.Fields(strings.ToLower(context)), " ")
+    for _, phrase := range sv.Phrases {
+        if containsWord(lowerContext, phrase) {
+            return SuppressedByPhrase, phrase, true
+        }
+    }
+    for _, word := range sv.Words {
+        if found, ok := keywords.findWord(lowerContext, word); ok {
+            return SuppressedByWord, found, true
+        }
+    }
+    return "", "", false
+}
+
+// credentialPart returns the part of a match that holds the secret: the user
+// info of a connection string, whose host (db-latest, contest-db) names a
+// server rather than a test value, or else the whole match
+func credentialPart(match string) string {
+    scheme := strings.Index(match, "://")
+    if scheme == -1 {
+        return match
+    }
+    authority := match[scheme+3:]
+    if end := strings.IndexAny(authority, "/?#"); end != -1 {
+        authority = authority[:end]
+    }
+    if at := strings.LastIndexByte(authority, '@'); at != -1 {
+        return authority[:at]
+    }
+    return match
+}
+
+// SetSuppressionVocabulary switches subsequent scans to a vocabulary, nil
+// restores the built-in one
+func (s *SecretScanner) SetSuppressionVocabulary(vocabulary *SuppressionVocabulary) {
+    if vocabulary == nil {
+        vocabulary = defaultSuppression
+    }
+    s.suppression.Store(vocabulary)
+}
+
+// suppressionVocabulary returns the active vocabulary
+func (s *SecretScanner) suppressionVocabulary() *SuppressionVocabulary {
+    if vocabulary := s.suppression.Load(); vocabulary != nil {
+        return vocabulary
+    }
+    return defaultSuppression
+}
+
+// Parsed once, the built-in vocabulary is immutable
+var defaultSuppression = DefaultSuppressionVocabulary()
+
+// suppressionLog keeps the most recent discarded matches
+type suppressionLog struct {
+    mutex   sync.Mutex
+    entries []models.SuppressedCandidate
+}
+
+func (sl *suppressionLog) add(candidates []models.SuppressedCandidate) {
+    sl.mutex.Lock()
+    defer sl.mutex.Unlock()
+    sl.entries = append(sl.entries, candidates...)
+    if excess := len(sl.entries) - maxSuppressionLog; excess > 0 {
+        sl.entries = append([]models.SuppressedCandidate(nil), sl.entries[excess:]...)
+    }
+}
+
+// Suppressions returns up to limit of the most recently discarded matches,
+// newest first; limit <= 0 returns all that are kept
+func (s *SecretScanner) Suppressions(limit int) []models.SuppressedCandidate {
+    s.suppressions.mutex.Lock()
+    defer s.suppressions.mutex.Unlock()
+
+    entries := s.suppressions.entries
+    if limit <= 0 || limit > len(entries) {
+        limit = len(entries)
+    }
+    newest := make([]models.SuppressedCandidate, 0, limit)
+    for i := len(entries) - 1; i >= 0 && len(newest) < limit; i-- {
+        newest = append(newest, entries[i])
+    }
+    return newest
+}
+
+// suppress records a discarded match of pattern once per position and rule.
+// Passes that only look for extra candidates do not record.
+func (p *scanPass) suppress(pattern SecretPattern, match, context string, startOffset int, rule, trigger string, confidence float64) {
+    if p.suppressed == nil {
+        return
+    }
+    key := fmt.Sprintf("%s/%d/%s", pattern.ID, startOffset, rule)
+    if _, seen := p.suppressed[key]; seen {
+        return
+    }
+    p.suppressed[key] = models.SuppressedCandidate{
+        MessageID:    p.msg.ID,
+        ChannelID:    p.msg.ChannelID,
+        TeamID:       p.msg.TeamID,
+        RuleID:       pattern.ID,
+        SecretType:   pattern.Name,
+        MaskedValue:  pattern.mask(match),
+        Context:      context,
+        StartOffset:  startOffset,
+        Rule:         rule,
+        Trigger:      trigger,
+        Confidence:   confidence,
+        SuppressedAt: time.Now(),
+    }
+}
+
+// flushSuppressions adds the pass's discarded matches to the scanner's log in message order
+func (p *scanPass) flushSuppressions() {
+    if len(p.suppressed) == 0 {
+        return
+    }
+    candidates := make([]models.SuppressedCandidate, 0, len(p.suppressed))
+    for _, candidate := range p.suppressed {
+        candidates = append(candidates, candidate)
+    }
+    sort.Slice(candidates, func(i, j int) bool {
+        if candidates[i].StartOffset != candidates[j].StartOffset {
+            return candidates[i].StartOffset < candidates[j].StartOffset
+        }
+        if candidates[i].RuleID != candidates[j].RuleID {
+            return candidates[i].RuleID < candidates[j].RuleID
+        }
+        return candidates[i].Rule < candidates[j].Rule
+    })
+    p.scanner.suppressions.add(candidates)
+}
@@ -0,0 +1,144 @@
+package detector
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Block-level elements in Teams message HTML that start a new line of text
+var htmlBlockTags = map[string]bool{
+    "p": true, "div": true, "br": true, "li": true, "tr": true,
+    "ul": true, "ol": true, "table": true, "blockquote": true,
+    "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+var hrefAttrPattern = regexp.MustCompile(`(?i)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+
+// anchorState tracks an open <a> element so its href can be scanned separately
+type anchorState struct {
+    href      string
+    hrefStart int
+    text      strings.Builder
+}
+
+// extractTeamsHTML converts a Teams "html" message body into plain text for
+// scanning. Mentions (<at>) are dropped, block elements become line breaks,
+// text inside <code>/<pre> is marked as a code block, and link targets that
+// differ from the visible link text are appended and marked as hrefs. Every
+// byte of the result stays mapped to the raw HTML it came from.
+func extractTeamsHTML(content string) normalizedText {
+    var tb textBuilder
+    tokenizer := html.NewTokenizer(strings.NewReader(content))
+
+    offset := 0
+    codeDepth, mentionDepth, skipDepth := 0, 0, 0
+    var anchor *anchorState
+
+    newline := func(start, end int) {
+        tb.writeString("\n", start, end)
+    }
+
+    for {
+        tokenType := tokenizer.Next()
+        if tokenType == html.ErrorToken {
+            break
+        }
+        raw := string(tokenizer.Raw())
+        start, end := offset, offset+len(raw)
+        offset = end
+
+        switch tokenType {
+        case html.TextToken:
+            if mentionDepth > 0 || skipDepth > 0 {
+                continue
+            }
+            tb.writeDecoded(raw, start)
+            if codeDepth > 0 {
+                tb.markRegion(regionCodeBlock, start, end)
+            }
+            if anchor != nil {
+                anchor.text.WriteString(html.UnescapeString(raw))
+            }
+
+        case html.StartTagToken, html.SelfClosingTagToken:
+            name, _ := tokenizer.TagName()
+            tag := string(name)
+            selfClosing := tokenType == html.SelfClosingTagToken
+
+            switch {
+            case tag == "at":
+                if !selfClosing {
+                    mentionDepth++
+                }
+            case tag == "script" || tag == "style":
+                if !selfClosing {
+                    skipDepth++
+                }
+            case tag == "pre" || tag == "code":
+                if tag == "pre" {
+                    newline(start, end)
+                }
+                if !selfClosing {
+                    codeDepth++
+                }
+            case tag == "a":
+                if loc := hrefAttrPattern.FindStringSubmatchIndex(raw); loc != nil {
+                    for group := 2; group < len(loc); group += 2 {
+                        if loc[group] >= 0 {
+                            anchor = &anchorState{
+                                href:      raw[loc[group]:loc[group+1]],
+                                hrefStart: start + loc[group],
+                            }
+                            break
+                        }
+                    }
+                }
+            case htmlBlockTags[tag]:
+                newline(start, end)
+            }
+
+        case html.EndTagToken:
+            name, _ := tokenizer.TagName()
+            switch tag := string(name); {
+            case tag == "at":
+                if mentionDepth > 0 {
+                    mentionDepth--
+                }
+            case tag == "script" || tag == "style":
+                if skipDepth > 0 {
+                    skipDepth--
+                }
+            case tag == "pre" || tag == "code":
+                if codeDepth > 0 {
+                    codeDepth--
+                }
+                if tag == "pre" {
+                    newline(start, end)
+                }
+            case tag == "a":
+                if anchor != nil {
+                    writeAnchorHref(&tb, anchor, start, end)
+                    anchor = nil
+                }
+            case htmlBlockTags[tag]:
+                newline(start, end)
+            }
+        }
+    }
+
+    return tb.build(content)
+}
+
+// writeAnchorHref appends a link target unless it is already the visible link text
+func writeAnchorHref(tb *textBuilder, anchor *anchorState, tagStart, tagEnd int) {
+    href := strings.TrimSpace(html.UnescapeString(anchor.href))
+    if href == "" || href == strings.TrimSpace(anchor.text.String()) {
+        return
+    }
+    tb.writeString(" ", tagStart, tagEnd)
+    tb.writeDecoded(anchor.href, anchor.hrefStart)
+    tb.markRegion(regionLinkHref, anchor.hrefStart, anchor.hrefStart+len(anchor.href))
+    tb.writeString(" ", tagStart, tagEnd)
+}
@@ -0,0 +1,226 @@
+package detector
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"hash/crc32"
+	"regexp"
+	"strings"
+)
+
+// ValidationResult is the outcome of an offline structural check on a match
+type ValidationResult struct {
+    Valid  bool
+    Reason string
+    // Inconclusive means the check could not run to completion (e.g. a truncated key)
+    // and the score is left unchanged
+    Inconclusive bool
+    // Veto means the match is structurally impossible for its type and must be dropped
+    Veto bool
+}
+
+// tokenValidator checks a matched value without any network access
+type tokenValidator func(secret string) ValidationResult
+
+// Validators that rules can reference by name through their `validator` field
+var tokenValidators = map[string]tokenValidator{
+    "aws-secret":      validateAWSSecret,
+    "github-checksum": validateGitHubChecksum,
+    "jwt":             validateJWT,
+    "pem":             validatePEM,
+    "password":        validatePassword,
+}
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// validateGitHubChecksum verifies the CRC32 checksum GitHub appends to its
+// current token formats: the last 6 characters are the base62 CRC32 of the
+// random part between the prefix and the checksum.
+func validateGitHubChecksum(secret string) ValidationResult {
+    underscore := strings.LastIndex(secret, "_")
+    if underscore == -1 || len(secret)-underscore-1 < 7 {
+        return ValidationResult{Reason: "no checksum segment"}
+    }
+
+    body := secret[underscore+1:]
+    random, checksum := body[:len(body)-6], body[len(body)-6:]
+
+    expected := encodeBase62(crc32.ChecksumIEEE([]byte(random)))
+    if len(expected) < 6 {
+        expected = strings.Repeat("0", 6-len(expected)) + expected
+    }
+
+    // GitHub issues every token in these formats with its checksum, so a
+    // mismatch is a made-up or mistyped value rather than a leaked token
+    if checksum != expected {
+        return ValidationResult{Reason: "checksum mismatch", Veto: true}
+    }
+    return ValidationResult{Valid: true, Reason: "CRC32 checksum matches"}
+}
+
+func encodeBase62(n uint32) string {
+    if n == 0 {
+        return "0"
+    }
+    var out []byte
+    for n > 0 {
+        out = append([]byte{base62Alphabet[n%62]}, out...)
+        n /= 62
+    }
+    return string(out)
+}
+
+// validateJWT decodes the header and payload; a header that is not JSON with
+// an "alg" means the match is not a JWT at all
+func validateJWT(secret string) ValidationResult {
+    parts := strings.Split(secret, ".")
+    if len(parts) != 3 {
+        return ValidationResult{Reason: "expected 3 segments", Veto: true}
+    }
+
+    var header map[string]interface{}
+    if err := decodeJWTSegment(parts[0], &header); err != nil {
+        return ValidationResult{Reason: "header is not base64url JSON", Veto: true}
+    }
+    alg, _ := header["alg"].(string)
+    if alg == "" {
+        return ValidationResult{Reason: "header has no alg", Veto: true}
+    }
+
+    var payload map[string]interface{}
+    if err := decodeJWTSegment(parts[1], &payload); err != nil {
+        return ValidationResult{Reason: "payload is not base64url JSON"}
+    }
+    if parts[2] == "" {
+        return ValidationResult{Reason: fmt.Sprintf("unsigned token (alg=%s)", alg)}
+    }
+
+    return ValidationResult{Valid: true, Reason: fmt.Sprintf("decodes as JWT (alg=%s)", alg)}
+}
+
+func decodeJWTSegment(segment string, v interface{}) error {
+    data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+    if err != nil {
+        return err
+    }
+    return json.Unmarshal(data, v)
+}
+
+// validatePEM parses the block as a private key. Truncated or elided keys are
+// inconclusive rather than invalid, a partial private key in chat is still a leak.
+func validatePEM(secret string) ValidationResult {
+    block, _ := pem.Decode([]byte(secret))
+    if block == nil {
+        return ValidationResult{Reason: "PEM body does not decode", Inconclusive: true}
+    }
+
+    switch block.Type {
+    case "RSA PRIVATE KEY":
+        if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+            return ValidationResult{Valid: true, Reason: "parses as PKCS#1 RSA private key"}
+        }
+    case "EC PRIVATE KEY":
+        if _, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+            return ValidationResult{Valid: true, Reason: "parses as EC private key"}
+        }
+    case "PRIVATE KEY":
+        if _, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+            return ValidationResult{Valid: true, Reason: "parses as PKCS#8 private key"}
+        }
+    case "OPENSSH PRIVATE KEY":
+        if bytes.HasPrefix(block.Bytes, []byte("openssh-key-v1\x00")) {
+            return ValidationResult{Valid: true, Reason: "OpenSSH private key header present"}
+        }
+    default:
+        // DSA, encrypted and PGP blocks are not parsed here (the latter need a
+        // passphrase or keyring), so decoding alone earns no boost
+        return ValidationResult{Reason: fmt.Sprintf("%s block is not parsed", block.Type), Inconclusive: true}
+    }
+
+    return ValidationResult{Reason: fmt.Sprintf("%s does not parse", block.Type), Inconclusive: true}
+}
+
+// Words that follow "password is/:" in ordinary sentences and config docs
+var passwordStopwords = map[string]bool{
+    "required": true, "incorrect": true, "wrong": true, "invalid": true, "expired": true,
+    "correct": true, "changed": true, "reset": true, "missing": true, "empty": true,
+    "set": true, "stored": true, "saved": true, "same": true, "different": true,
+    "still": true, "now": true, "also": true, "being": true, "hidden": true,
+    "masked": true, "redacted": true, "removed": true, "null": true, "none": true,
+    "nil": true, "undefined": true, "true": true, "false": true, "yes": true,
+    "your": true, "their": true, "what": true, "that": true, "this": true,
+    "password": true, "passwd": true, "pass": true, "pwd": true, "secret": true,
+    "in": true, "on": true, "the": true, "not": true, "too": true, "from": true,
+}
+
+var (
+    filesystemPathPattern = regexp.MustCompile(`^(?:~?/|\.\.?/|[A-Za-z]:\\)`)
+    dictionaryWordPattern = regexp.MustCompile(`^(?:[a-z]+|[A-Z][a-z]+|[A-Z]+)$`)
+)
+
+// validatePassword rejects values that are references or prose rather than a
+// password. Mixed character classes count as a real-looking password.
+func validatePassword(secret string) ValidationResult {
+    lower := strings.ToLower(strings.TrimRight(secret, ".!?:"))
+    if passwordStopwords[lower] {
+        return ValidationResult{Reason: "ordinary word, not a password", Veto: true}
+    }
+
+    // Environment/template references and redaction markers
+    if strings.HasPrefix(secret, "$") || strings.HasPrefix(secret, "%") || strings.HasPrefix(secret, "{{") {
+        return ValidationResult{Reason: "variable reference", Veto: true}
+    }
+    if strings.Trim(secret, "*x•.#") == "" {
+        return ValidationResult{Reason: "already redacted", Veto: true}
+    }
+    // Shell output such as "pwd = /home/alice/projects"
+    if filesystemPathPattern.MatchString(secret) {
+        return ValidationResult{Reason: "filesystem path", Veto: true}
+    }
+    // A lone word in one case ("the password was valid") is prose
+    if dictionaryWordPattern.MatchString(secret) {
+        return ValidationResult{Reason: "dictionary word, not a password", Veto: true}
+    }
+
+    var upper, lowerCase, digit, symbol bool
+    for _, r := range secret {
+        switch {
+        case r >= 'A' && r <= 'Z':
+            upper = true
+        case r >= 'a' && r <= 'z':
+            lowerCase = true
+        case r >= '0' && r <= '9':
+            digit = true
+        default:
+            symbol = true
+        }
+    }
+    classes := 0
+    for _, has := range []bool{upper, lowerCase, digit, symbol} {
+        if has {
+            classes++
+        }
+    }
+
+    if classes >= 3 && len(secret) >= 8 {
+        return ValidationResult{Valid: true, Reason: fmt.Sprintf("%d character classes", classes)}
+    }
+    return ValidationResult{Reason: "weak or dictionary-like password", Inconclusive: true}
+}
+
+// validateAWSSecret rejects 40-character values that cannot be an AWS secret
+// access key. Those are random base64 and so mix upper and lower case; commit
+// SHAs and other hex digests are single-case.
+func validateAWSSecret(secret string) ValidationResult {
+    if strings.Trim(strings.ToLower(secret), "0123456789abcdef") == "" {
+        return ValidationResult{Reason: "hexadecimal digest, not base64", Veto: true}
+    }
+    if strings.ToLower(secret) == secret || strings.ToUpper(secret) == secret {
+        return ValidationResult{Reason: "single-case, not base64", Veto: true}
+    }
+    return ValidationResult{Reason: "mixed-case base64", Inconclusive: true}
+}
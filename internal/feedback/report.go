@@ -0,0 +1,124 @@
+package feedback
+
+import (
+	"sort"
+
+	"stackguard-task/internal/detector"
+)
+
+// Report shows how a retrained calibration moves scores on the labelled samples
+type Report struct {
+    Samples        int           `json:"samples"`
+    Real           int           `json:"real"`
+    FalsePositives int           `json:"falsePositives"`
+    Weights        []WeightShift `json:"weights"`
+    Before         ScoreSummary  `json:"before"`
+    After          ScoreSummary  `json:"after"`
+    Rules          []RuleShift   `json:"rules"`
+}
+
+// WeightShift is one factor weight before and after retraining
+type WeightShift struct {
+    Factor string  `json:"factor"`
+    Before float64 `json:"before"`
+    After  float64 `json:"after"`
+}
+
+// ScoreSummary summarises scores over the labelled samples. Accuracy counts a
+// sample as right when its score is on the analyst's side of 0.5.
+type ScoreSummary struct {
+    MeanReal          float64 `json:"meanReal"`
+    MeanFalsePositive float64 `json:"meanFalsePositive"`
+    Accuracy          float64 `json:"accuracy"`
+}
+
+// RuleShift is the mean score of one rule's labelled samples before and after
+type RuleShift struct {
+    RuleID         string  `json:"ruleId"`
+    Real           int     `json:"real"`
+    FalsePositives int     `json:"falsePositives"`
+    Shift          float64 `json:"shift"` // learned log-odds shift for the rule
+    Before         float64 `json:"before"`
+    After          float64 `json:"after"`
+}
+
+// Compare scores samples under the before calibration (nil for the defaults)
+// and the after calibration
+func Compare(samples []Sample, before, after *detector.Calibration) Report {
+    report := Report{Samples: len(samples)}
+
+    for _, name := range detector.ConfidenceFactorNames() {
+        report.Weights = append(report.Weights, WeightShift{
+            Factor: name,
+            Before: before.Weight(name),
+            After:  after.Weight(name),
+        })
+    }
+
+    rules := make(map[string]*RuleShift)
+    beforeScores := make([]float64, len(samples))
+    afterScores := make([]float64, len(samples))
+    for i, sample := range samples {
+        beforeScores[i] = Score(sample, before)
+        afterScores[i] = Score(sample, after)
+
+        rule, ok := rules[sample.RuleID]
+        if !ok {
+            rule = &RuleShift{RuleID: sample.RuleID}
+            if after != nil {
+                rule.Shift = after.Rules[sample.RuleID]
+            }
+            rules[sample.RuleID] = rule
+        }
+        if sample.Real {
+            report.Real++
+            rule.Real++
+        } else {
+            report.FalsePositives++
+            rule.FalsePositives++
+        }
+        rule.Before += beforeScores[i]
+        rule.After += afterScores[i]
+    }
+
+    report.Before = summarise(samples, beforeScores)
+    report.After = summarise(samples, afterScores)
+
+    for _, rule := range rules {
+        count := float64(rule.Real + rule.FalsePositives)
+        rule.Before = round(rule.Before / count)
+        rule.After = round(rule.After / count)
+        report.Rules = append(report.Rules, *rule)
+    }
+    sort.Slice(report.Rules, func(i, j int) bool {
+        return report.Rules[i].RuleID < report.Rules[j].RuleID
+    })
+    return report
+}
+
+func summarise(samples []Sample, scores []float64) ScoreSummary {
+    var summary ScoreSummary
+    var real, falsePositive, correct int
+    for i, sample := range samples {
+        if sample.Real {
+            summary.MeanReal += scores[i]
+            real++
+        } else {
+            summary.MeanFalsePositive += scores[i]
+            falsePositive++
+        }
+        if (scores[i] >= 0.5) == sample.Real {
+            correct++
+        }
+    }
+    if real > 0 {
+        summary.MeanReal = round(summary.MeanReal / float64(real))
+    }
+    if falsePositive > 0 {
+        summary.MeanFalsePositive = round(summary.MeanFalsePositive / float64(falsePositive))
+    }
+    if len(samples) > 0 {
+        summary.Accuracy = round(float64(correct) / float64(len(samples)))
+    }
+    return summary
+}
@@ -0,0 +1,100 @@
+package feedback
+
+import (
+	"math"
+
+	"stackguard-task/internal/constants"
+	"stackguard-task/internal/detector"
+	"stackguard-task/internal/models"
+)
+
+// Sample is an analyst-labelled detection reduced to what the feedback model
+// learns from. It holds no secret values, so sample files can be shared.
+type Sample struct {
+    DetectionID string             `json:"detectionId"`
+    RuleID      string             `json:"ruleId"`
+    ChannelID   string             `json:"channelId"`
+    Factors     map[string]float64 `json:"factors"`
+    Tokens      []string           `json:"tokens"` // words of the masked context
+    // Penalty is the ratio of the score after false-positive penalties to the
+    // weighted score before them; re-scoring keeps it
+    Penalty    float64 `json:"penalty"`
+    Validated  bool    `json:"validated"` // passed structural validation
+    Confidence float64 `json:"confidence"`
+    Real       bool    `json:"real"` // resolved as a real secret rather than marked false_positive
+}
+
+// SamplesFromDetections labels detections that analysts resolved (real) or
+// marked false_positive. Detections in other states, or stored without a
+// confidence explanation, carry no usable label and are skipped.
+func SamplesFromDetections(detections []models.SecretDetection) []Sample {
+    var samples []Sample
+    for _, detection := range detections {
+        if detection.Status != constants.StatusResolved && detection.Status != constants.StatusFalsePositive {
+            continue
+        }
+        explanation := detection.Explanation
+        if explanation == nil || len(explanation.Factors) == 0 {
+            continue
+        }
+
+        factors := make(map[string]float64, len(explanation.Factors))
+        for _, factor := range explanation.Factors {
+            factors[factor.Name] = factor.Score
+        }
+        // Grouped config file detections are not scored by the weighted factors
+        if _, ok := factors["specificity"]; !ok {
+            continue
+        }
+
+        // The penalties sit between any earlier feedback shift and validation
+        before, after := explanation.BaseScore, explanation.Confidence
+        validated := false
+        for _, adjustment := range explanation.Adjustments {
+            switch adjustment.Reason {
+            case detector.FeedbackReason:
+                before = adjustment.After
+            case detector.PassedValidationReason:
+                after, validated = adjustment.Before, true
+            }
+        }
+        penalty := 0.0
+        if before > 0 {
+            penalty = after / before
+        }
+
+        samples = append(samples, Sample{
+            DetectionID: detection.ID,
+            RuleID:      detection.RuleID,
+            ChannelID:   detection.ChannelID,
+            Factors:     factors,
+            Tokens:      detector.ContextTokens(detection.Context),
+            Penalty:     penalty,
+            Validated:   validated,
+            Confidence:  detection.Confidence,
+            Real:        detection.Status == constants.StatusResolved,
+        })
+    }
+    return samples
+}
+
+// Score re-scores a sample under a calibration (nil for the default weights)
+func Score(sample Sample, calibration *detector.Calibration) float64 {
+    var weightedSum, totalWeight float64
+    for _, name := range detector.ConfidenceFactorNames() {
+        weight := calibration.Weight(name)
+        weightedSum += sample.Factors[name] * weight
+        totalWeight += weight
+    }
+    if totalWeight == 0 {
+        return 0
+    }
+
+    score := weightedSum / totalWeight
+    score = detector.ApplyShift(score, calibration.FeedbackShift(sample.RuleID, sample.ChannelID, sample.Tokens))
+    score *= sample.Penalty
+    if sample.Validated {
+        score += (1.0 - score) * 0.5
+    }
+    return math.Max(0.0, math.Min(score, 1.0))
+}
@@ -0,0 +1,223 @@
+package feedback
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"stackguard-task/internal/detector"
+)
+
+// ErrNotEnoughFeedback is returned when there are too few labels, or labels
+// of only one kind, to learn anything from
+var ErrNotEnoughFeedback = errors.New("not enough analyst feedback to retrain: need at least 10 labelled detections including both real secrets and false positives")
+
+const (
+    minSamples = 10
+
+    // Gradient descent settings for the factor weights
+    iterations   = 2000
+    learningRate = 0.5
+    l2Penalty    = 0.01
+
+    // Learned weights are blended with the defaults as if the defaults had
+    // been fitted on this many samples, so a little feedback moves them a little
+    priorSamples = 50.0
+
+    // Rules, channels and tokens need this many labels before they get a shift,
+    // and their rates are smoothed towards the overall rate with this weight
+    minSupport      = 3
+    smoothingWeight = 5.0
+    // Token shifts are damped because many tokens add up on one detection
+    tokenScale    = 0.5
+    maxTokenShift = 1.0
+    maxTokens     = 200
+)
+
+// Train learns a calibration from labelled samples. Factor weights come from
+// a logistic regression over the factor scores, blended with the defaults;
+// rules, channels and context tokens get smoothed log-odds shifts for how much
+// more or less often than average analysts confirmed them as real.
+func Train(samples []Sample) (*detector.Calibration, error) {
+    real := 0
+    for _, sample := range samples {
+        if sample.Real {
+            real++
+        }
+    }
+    if len(samples) < minSamples || real == 0 || real == len(samples) {
+        return nil, ErrNotEnoughFeedback
+    }
+
+    baseRate := (float64(real) + 1) / (float64(len(samples)) + 2)
+
+    calibration := &detector.Calibration{
+        TrainedAt: time.Now(),
+        Samples:   len(samples),
+        Weights:   fitWeights(samples),
+        Rules:     groupShifts(samples, baseRate, func(s Sample) string { return s.RuleID }),
+        Channels:  groupShifts(samples, baseRate, func(s Sample) string { return s.ChannelID }),
+        Tokens:    tokenShifts(samples, baseRate),
+    }
+    return calibration, nil
+}
+
+// fitWeights fits a logistic regression of the label on the factor scores and
+// turns the coefficients into weights summing to 1. A factor that pushes
+// towards false positives gets no weight rather than a negative one.
+func fitWeights(samples []Sample) map[string]float64 {
+    names := detector.ConfidenceFactorNames()
+    coefficients := make([]float64, len(names))
+    var intercept float64
+    n := float64(len(samples))
+
+    for iter := 0; iter < iterations; iter++ {
+        gradients := make([]float64, len(names))
+        var interceptGradient float64
+        for _, sample := range samples {
+            z := intercept
+            for i, name := range names {
+                z += coefficients[i] * sample.Factors[name]
+            }
+            residual := sigmoid(z) - label(sample)
+            for i, name := range names {
+                gradients[i] += residual * sample.Factors[name]
+            }
+            interceptGradient += residual
+        }
+        for i := range coefficients {
+            coefficients[i] -= learningRate * (gradients[i]/n + l2Penalty*coefficients[i])
+        }
+        intercept -= learningRate * interceptGradient / n
+    }
+
+    var total float64
+    for i := range coefficients {
+        coefficients[i] = math.Max(coefficients[i], 0)
+        total += coefficients[i]
+    }
+
+    defaults := detector.DefaultFactorWeights()
+    learnedShare := n / (n + priorSamples)
+    weights := make(map[string]float64, len(names))
+    for i, name := range names {
+        learned := defaults[name]
+        if total > 0 {
+            learned = coefficients[i] / total
+        }
+        weights[name] = round(learnedShare*learned + (1-learnedShare)*defaults[name])
+    }
+    return weights
+}
+
+// groupShifts gives each key with enough labels its shift from the overall rate
+func groupShifts(samples []Sample, baseRate float64, key func(Sample) string) map[string]float64 {
+    type counts struct{ real, total int }
+    groups := make(map[string]*counts)
+    for _, sample := range samples {
+        k := key(sample)
+        if k == "" {
+            continue
+        }
+        c, ok := groups[k]
+        if !ok {
+            c = &counts{}
+            groups[k] = c
+        }
+        c.total++
+        if sample.Real {
+            c.real++
+        }
+    }
+
+    shifts := make(map[string]float64)
+    for k, c := range groups {
+        if c.total < minSupport {
+            continue
+        }
+        if shift := round(rateShift(c.real, c.total, baseRate)); shift != 0 {
+            shifts[k] = shift
+        }
+    }
+    return shifts
+}
+
+// tokenShifts gives context tokens a damped shift from the overall rate,
+// keeping the strongest
+func tokenShifts(samples []Sample, baseRate float64) map[string]float64 {
+    type counts struct{ real, total int }
+    tokens := make(map[string]*counts)
+    for _, sample := range samples {
+        for _, token := range sample.Tokens {
+            c, ok := tokens[token]
+            if !ok {
+                c = &counts{}
+                tokens[token] = c
+            }
+            c.total++
+            if sample.Real {
+                c.real++
+            }
+        }
+    }
+
+    type scored struct {
+        token string
+        shift float64
+    }
+    var candidates []scored
+    for token, c := range tokens {
+        if c.total < minSupport {
+            continue
+        }
+        shift := tokenScale * rateShift(c.real, c.total, baseRate)
+        shift = round(math.Max(-maxTokenShift, math.Min(shift, maxTokenShift)))
+        if shift != 0 {
+            candidates = append(candidates, scored{token, shift})
+        }
+    }
+
+    sort.Slice(candidates, func(i, j int) bool {
+        if math.Abs(candidates[i].shift) != math.Abs(candidates[j].shift) {
+            return math.Abs(candidates[i].shift) > math.Abs(candidates[j].shift)
+        }
+        return candidates[i].token < candidates[j].token
+    })
+    if len(candidates) > maxTokens {
+        candidates = candidates[:maxTokens]
+    }
+
+    shifts := make(map[string]float64, len(candidates))
+    for _, candidate := range candidates {
+        shifts[candidate.token] = candidate.shift
+    }
+    return shifts
+}
+
+// rateShift is the log-odds difference between a group's rate of real
+// secrets, smoothed towards baseRate, and baseRate itself
+func rateShift(real, total int, baseRate float64) float64 {
+    rate := (float64(real) + smoothingWeight*baseRate) / (float64(total) + smoothingWeight)
+    return logit(rate) - logit(baseRate)
+}
+
+func label(sample Sample) float64 {
+    if sample.Real {
+        return 1
+    }
+    return 0
+}
+
+func sigmoid(z float64) float64 {
+    return 1 / (1 + math.Exp(-z))
+}
+
+func logit(p float64) float64 {
+    return math.Log(p / (1 - p))
+}
+
+// round keeps saved calibrations readable
+func round(value float64) float64 {
+    return math.Round(value*1000) / 1000
+}
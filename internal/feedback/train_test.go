@@ -0,0 +1,90 @@
+package feedback
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"stackguard-task/internal/detector"
+)
+
+// labelled returns n samples of one rule; real ones score high on specificity
+func labelled(n int, ruleID string, real bool) []Sample {
+    specificity := 0.2
+    if real {
+        specificity = 0.9
+    }
+    samples := make([]Sample, n)
+    for i := range samples {
+        samples[i] = Sample{
+            RuleID:    ruleID,
+            ChannelID: "c1",
+            Factors: map[string]float64{
+                "specificity": specificity,
+                "entropy":     0.6,
+                "context":     0.5,
+                "length":      0.7,
+                "composition": 0.5,
+            },
+            Tokens:  []string{"token"},
+            Penalty: 1,
+            Real:    real,
+        }
+    }
+    return samples
+}
+
+func TestTrainNeedsBothLabels(t *testing.T) {
+    tests := []struct {
+        name    string
+        samples []Sample
+    }{
+        {name: "no samples"},
+        {name: "too few", samples: append(labelled(3, "aws-access-key", true), labelled(3, "generic-api-key", false)...)},
+        {name: "only real secrets", samples: labelled(12, "aws-access-key", true)},
+        {name: "only false positives", samples: labelled(12, "generic-api-key", false)},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if _, err := Train(tt.samples); !errors.Is(err, ErrNotEnoughFeedback) {
+                t.Errorf("error = %v, want ErrNotEnoughFeedback", err)
+            }
+        })
+    }
+}
+
+func TestTrain(t *testing.T) {
+    samples := append(labelled(10, "aws-access-key", true), labelled(10, "generic-api-key", false)...)
+    calibration, err := Train(samples)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var total float64
+    for _, name := range detector.ConfidenceFactorNames() {
+        weight, ok := calibration.Weights[name]
+        if !ok || weight < 0 {
+            t.Errorf("weight for %s = %v, %v", name, weight, ok)
+        }
+        total += weight
+    }
+    if math.Abs(total-1) > 0.01 {
+        t.Errorf("weights sum to %v, want 1", total)
+    }
+    // Specificity separates the labels, so it gains weight over the default
+    if calibration.Weights["specificity"] <= detector.DefaultFactorWeights()["specificity"] {
+        t.Errorf("specificity weight %v did not grow", calibration.Weights["specificity"])
+    }
+
+    if shift := calibration.Rules["generic-api-key"]; shift >= 0 {
+        t.Errorf("shift for a rule analysts always dismiss = %v, want negative", shift)
+    }
+    if shift := calibration.Rules["aws-access-key"]; shift <= 0 {
+        t.Errorf("shift for a rule analysts always confirm = %v, want positive", shift)
+    }
+    // Every sample shares the channel, so it says nothing
+    if shift := calibration.Channels["c1"]; shift != 0 {
+        t.Errorf("shift for a channel at the average rate = %v, want 0", shift)
+    }
+}
@@ -5,13 +5,17 @@ import (
 )
 
 type TeamsMessage struct {
-	ID          string      `json:"id"`
-	CreatedAt   time.Time   `json:"createdDateTime"`
-	From        MessageFrom `json:"from"`
-	Body        MessageBody `json:"body"`
-	ChannelID   string      `json:"channelId"`
-	TeamID      string      `json:"teamId"`
-	WebURL      string      `json:"webUrl"`
+	ID          string              `json:"id"`
+	CreatedAt   time.Time           `json:"createdDateTime"`
+	From        MessageFrom         `json:"from"`
+	Body        MessageBody         `json:"body"`
+	ChannelID   string              `json:"channelId"`
+	TeamID      string              `json:"teamId"`
+	WebURL      string              `json:"webUrl"`
+	ReplyToID   string              `json:"replyToId,omitempty"`  // message this one replies to, empty for a thread root
+	ThreadID    string              `json:"threadId,omitempty"`   // root message of the thread, defaults to ReplyToID or the message itself
+	ParentBody  *MessageBody        `json:"parentBody,omitempty"` // body of the replied-to message, when the sender includes it
+	Attachments []MessageAttachment `json:"attachments,omitempty"`
 }
 
 type MessageFrom struct {
@@ -27,21 +31,93 @@ type MessageBody struct {
 	Content     string `json:"content"`
 }
 
+// MessageAttachment is a file, code snippet or card attached to a message
+type MessageAttachment struct {
+	ID          string `json:"id"`
+	ContentType string `json:"contentType"`
+	ContentURL  string `json:"contentUrl,omitempty"`
+	Content     string `json:"content,omitempty"` // inline content, e.g. a code snippet
+	Name        string `json:"name"`
+}
+
 type SecretDetection struct {
-	ID          string    `json:"id"`
-	MessageID   string    `json:"messageId"`
-	ChannelID   string    `json:"channelId"`
-	TeamID      string    `json:"teamId"`
-	UserID      string    `json:"userId"`
-	UserName    string    `json:"userName"`
-	SecretType  string    `json:"secretType"`
-	MaskedValue string    `json:"maskedValue"`
-	FullValue   string    `json:"-"` // Never serialize this
-	Confidence  float64   `json:"confidence"`
-	Context     string    `json:"context"`
-	DetectedAt  time.Time `json:"detectedAt"`
-	Severity    string    `json:"severity"`
-	Status      string    `json:"status"` // "new", "acknowledged", "resolved"
+	ID               string    `json:"id"`
+	MessageID        string    `json:"messageId"`
+	ChannelID        string    `json:"channelId"`
+	TeamID           string    `json:"teamId"`
+	UserID           string    `json:"userId"`
+	UserName         string    `json:"userName"`
+	SecretType       string    `json:"secretType"`
+	MaskedValue      string    `json:"maskedValue"`
+	FullValue        string    `json:"-"` // Never serialize this
+	Confidence       float64   `json:"confidence"`
+	Context          string    `json:"context"`
+	DetectedAt       time.Time `json:"detectedAt"`
+	Severity         string    `json:"severity"`
+	Status           string    `json:"status"` // "new", "acknowledged", "resolved"
+	RuleID           string    `json:"ruleId"`
+	StartOffset      int       `json:"startOffset"` // byte offset in the original message body
+	EndOffset        int       `json:"endOffset"`
+	Line             int       `json:"line"`   // 1-based
+	Column           int       `json:"column"` // 1-based, in characters
+	InCodeBlock      bool      `json:"inCodeBlock"`
+	InLinkHref       bool      `json:"inLinkHref"`
+	DecodedVia       []string  `json:"decodedVia,omitempty"` // decoders applied to reach the secret, outermost first
+	Deobfuscated     bool      `json:"deobfuscated"`         // only found after undoing zero-width, homoglyph, spacing or message-splitting tricks
+	Validated        bool      `json:"validated"`            // passed an offline structural check (checksum, JWT header, PEM parse)
+	ValidationReason string    `json:"validationReason,omitempty"`
+	SensitiveKeys    []string  `json:"sensitiveKeys,omitempty"` // credential keys found in a pasted config file (.env, kubeconfig, ...)
+	MessageIDs       []string  `json:"messageIds,omitempty"`    // every message a split secret was reassembled from, oldest first
+	ThreadID         string    `json:"threadId,omitempty"`
+	ReplyToID        string    `json:"replyToId,omitempty"`
+	Attachment       string    `json:"attachment,omitempty"`      // name of the attachment the secret is in, offsets refer to its text
+	AttachmentEntry  string    `json:"attachmentEntry,omitempty"` // path of the file inside an archive attachment
+	Profile          string    `json:"profile,omitempty"`         // scoring profile applied to the rule, channel or team
+	// Explanation is served by the explain endpoint only, it is too verbose for lists and alerts
+	Explanation *ConfidenceExplanation `json:"-"`
+}
+
+// ConfidenceExplanation shows how a detection's confidence was computed
+type ConfidenceExplanation struct {
+	Factors     []ConfidenceFactor     `json:"factors"`
+	BaseScore   float64                `json:"baseScore"`             // weighted average of the factors
+	Adjustments []ConfidenceAdjustment `json:"adjustments,omitempty"` // in the order they were applied
+	Confidence  float64                `json:"confidence"`
+	Languages   []string               `json:"languages,omitempty"` // languages besides English whose keywords were matched
+}
+
+// ConfidenceFactor is one weighted input of the base score
+type ConfidenceFactor struct {
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`
+	Weight float64 `json:"weight"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// ConfidenceAdjustment is a bonus or penalty applied to a factor or to the weighted score
+type ConfidenceAdjustment struct {
+	Factor  string  `json:"factor"` // factor adjusted, or "confidence" for the weighted score
+	Reason  string  `json:"reason"`
+	Trigger string  `json:"trigger,omitempty"` // keyword, pattern or check result that caused it
+	Before  float64 `json:"before"`
+	After   float64 `json:"after"`
+}
+
+// SuppressedCandidate records a match the scanner discarded, so silent drops
+// can be reviewed. Like detections it only keeps the masked value.
+type SuppressedCandidate struct {
+	MessageID    string    `json:"messageId"`
+	ChannelID    string    `json:"channelId"`
+	TeamID       string    `json:"teamId"`
+	RuleID       string    `json:"ruleId"`
+	SecretType   string    `json:"secretType"`
+	MaskedValue  string    `json:"maskedValue"`
+	Context      string    `json:"context"`
+	StartOffset  int       `json:"startOffset"`
+	Rule         string    `json:"rule"`              // suppression rule that discarded the match
+	Trigger      string    `json:"trigger,omitempty"` // word, phrase, pattern or check result that fired it
+	Confidence   float64   `json:"confidence,omitempty"`
+	SuppressedAt time.Time `json:"suppressedAt"`
 }
 
 type AlertRequest struct {
@@ -59,11 +135,13 @@ type DashboardStats struct {
 }
 
 type WebhookPayload struct {
-	Type         string       `json:"type"`
-	Message      TeamsMessage `json:"message"`
-	ChannelID    string       `json:"channelId"`
-	TeamID       string       `json:"teamId"`
-	Timestamp    time.Time    `json:"timestamp"`
+	Type      string       `json:"type"`
+	Message   TeamsMessage `json:"message"`
+	ChannelID string       `json:"channelId"`
+	TeamID    string       `json:"teamId"`
+	Timestamp time.Time    `json:"timestamp"`
+	// ParentMessage is the message replied to, sent along so its text can be used as context
+	ParentMessage *TeamsMessage `json:"parentMessage,omitempty"`
 }
 
 type APIResponse struct {
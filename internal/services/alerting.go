@@ -68,7 +68,7 @@ func (as *AlertService) formatAlertMessage(detection models.SecretDetection) str
 func (as *AlertService) GetAlertType(detection models.SecretDetection) string {
     if detection.Severity == "CRITICAL" || (detection.Severity == "HIGH" && detection.Confidence > 0.9) {
         return constants.AlertTypeCriticalRisk
-    } else if detection.Severity == "HIGH" || detection.Confidence > 0.8 {
+    } else if detection.Severity == "HIGH" || detection.Confidence > 0.8 || detection.Deobfuscated {
         return constants.AlertTypeHighRisk
     }
     return constants.AlertTypeSecretDetected
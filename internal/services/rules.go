@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"stackguard-task/internal/corpus"
+	"stackguard-task/internal/detector"
+	"stackguard-task/internal/models"
+)
+
+// ErrCorpusUnavailable is returned when the testcases corpora cannot be loaded
+var ErrCorpusUnavailable = errors.New("testcases corpus unavailable")
+
+// Dry-run outcomes for a single corpus case
+const (
+    DryRunCorrect   = "correct"   // change agrees with expected_detections
+    DryRunIncorrect = "incorrect" // change contradicts expected_detections
+)
+
+// RuleDryRunCase is a corpus case whose detections change under the candidate rule
+type RuleDryRunCase struct {
+    Description  string   `json:"description"`
+    Source       string   `json:"source"`
+    Expected     []string `json:"expected"`
+    Before       []string `json:"before"`
+    After        []string `json:"after"`
+    ChangedTypes []string `json:"changedTypes"`
+    Outcome      string   `json:"outcome"`
+}
+
+// RuleDryRunReport summarizes how a candidate rule would change corpus results
+type RuleDryRunReport struct {
+    Rule           detector.RuleDefinition `json:"rule"`
+    CasesEvaluated int                     `json:"casesEvaluated"`
+    NewHits        []RuleDryRunCase        `json:"newHits"`
+    NewMisses      []RuleDryRunCase        `json:"newMisses"`
+}
+
+func (ts *TeamsService) GetRulePack() *detector.RulePack {
+    return ts.scanner.RulePack()
+}
+
+func (ts *TeamsService) CreateRule(rule detector.RuleDefinition) error {
+    return ts.scanner.AddRule(rule)
+}
+
+func (ts *TeamsService) SetRuleEnabled(id string, enabled bool) error {
+    return ts.scanner.SetRuleEnabled(id, enabled)
+}
+
+func (ts *TeamsService) DeleteRule(id string) error {
+    return ts.scanner.DeleteRule(id)
+}
+
+// DryRunRule scans the testcases corpora with the active rules and again with
+// the candidate rule added (or replacing the rule with the same id), and
+// reports every case the candidate would newly hit or miss.
+func (ts *TeamsService) DryRunRule(rule detector.RuleDefinition) (*RuleDryRunReport, error) {
+    current := ts.scanner.RulePack()
+    candidatePack := current.Clone()
+    rule.Disabled = false
+
+    replaced := false
+    for i := range candidatePack.Rules {
+        if candidatePack.Rules[i].ID == rule.ID {
+            candidatePack.Rules[i] = rule
+            replaced = true
+            break
+        }
+    }
+    if !replaced {
+        candidatePack.Rules = append(candidatePack.Rules, rule)
+    }
+
+    // Both sides score like the live scanner, so only the rule differs
+    candidate, err := ts.scanner.WithRulePack(candidatePack)
+    if err != nil {
+        return nil, err
+    }
+    baseline, err := ts.scanner.WithRulePack(current)
+    if err != nil {
+        return nil, err
+    }
+
+    cases, err := corpus.LoadDir(ts.config.TestcasesDir)
+    if err != nil {
+        return nil, fmt.Errorf("%w: %v", ErrCorpusUnavailable, err)
+    }
+
+    report := &RuleDryRunReport{
+        Rule:           rule,
+        CasesEvaluated: len(cases),
+        NewHits:        []RuleDryRunCase{},
+        NewMisses:      []RuleDryRunCase{},
+    }
+
+    for i, tc := range cases {
+        message := tc.Message(i)
+        before := detectedTypes(baseline, message)
+        after := detectedTypes(candidate, message)
+        expected := toSet(tc.ExpectedDetections)
+
+        entry := RuleDryRunCase{
+            Description: tc.Description,
+            Source:      tc.Source,
+            Expected:    tc.ExpectedDetections,
+            Before:      sortedKeys(before),
+            After:       sortedKeys(after),
+        }
+
+        if hits := difference(after, before); len(hits) > 0 {
+            hitEntry := entry
+            hitEntry.ChangedTypes = hits
+            hitEntry.Outcome = outcomeFor(hits, expected, true)
+            report.NewHits = append(report.NewHits, hitEntry)
+        }
+        if misses := difference(before, after); len(misses) > 0 {
+            missEntry := entry
+            missEntry.ChangedTypes = misses
+            missEntry.Outcome = outcomeFor(misses, expected, false)
+            report.NewMisses = append(report.NewMisses, missEntry)
+        }
+    }
+
+    return report, nil
+}
+
+func detectedTypes(scanner *detector.SecretScanner, message models.TeamsMessage) map[string]bool {
+    types := make(map[string]bool)
+    for _, detection := range scanner.ScanMessage(message) {
+        types[detection.SecretType] = true
+    }
+    return types
+}
+
+// outcomeFor decides whether newly hit (or missed) types agree with the labels
+func outcomeFor(changed []string, expected map[string]bool, hit bool) string {
+    for _, secretType := range changed {
+        if expected[secretType] != hit {
+            return DryRunIncorrect
+        }
+    }
+    return DryRunCorrect
+}
+
+func toSet(values []string) map[string]bool {
+    set := make(map[string]bool, len(values))
+    for _, value := range values {
+        set[value] = true
+    }
+    return set
+}
+
+func difference(a, b map[string]bool) []string {
+    var diff []string
+    for key := range a {
+        if !b[key] {
+            diff = append(diff, key)
+        }
+    }
+    sort.Strings(diff)
+    return diff
+}
+
+func sortedKeys(set map[string]bool) []string {
+    keys := make([]string, 0, len(set))
+    for key := range set {
+        keys = append(keys, key)
+    }
+    sort.Strings(keys)
+    return keys
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"stackguard-task/internal/config"
+	"stackguard-task/internal/corpus"
+	"stackguard-task/internal/detector"
+)
+
+const dryRunCorpus = `[
+  {
+    "description": "Deploy token in prose",
+    "request_body": {"text": "deploy token itk_Zq8Vt3Lm9Rw2Xk7Pb4NcHs6FgJ1aEuQ0 for prod", "channelId": "infra", "userName": "Priya"},
+    "expected_detections": ["Internal API Token"]
+  }
+]`
+
+var dryRunRule = detector.RuleDefinition{
+    ID:          "internal-api-token",
+    Name:        "Internal API Token",
+    Pattern:     `itk_[A-Za-z0-9]{32}`,
+    Severity:    "HIGH",
+    Specificity: 0.6,
+    Keywords:    []string{"itk", "token"},
+}
+
+func writeFile(t *testing.T, path, data string) {
+    t.Helper()
+    if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestDryRunRuleUsesLiveSettings(t *testing.T) {
+    dir := t.TempDir()
+    writeFile(t, filepath.Join(dir, corpus.ShouldBeDetectedFile), dryRunCorpus)
+    writeFile(t, filepath.Join(dir, corpus.ShouldNotBeDetectedFile), "[]")
+
+    vocabularyPath := filepath.Join(dir, "suppression.yaml")
+    writeFile(t, vocabularyPath, "words: [deploy]\n")
+    vocabulary, err := detector.LoadSuppressionVocabulary(vocabularyPath)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    profilesPath := filepath.Join(dir, "profiles.yaml")
+    writeFile(t, profilesPath, "profiles:\n  silent: {minConfidence: 1.0}\nchannels: {infra: silent}\n")
+    profiles, err := detector.LoadProfiles(profilesPath)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        name      string
+        configure func(*detector.SecretScanner)
+        newHits   int
+    }{
+        {name: "defaults", configure: func(*detector.SecretScanner) {}, newHits: 1},
+        {name: "suppression vocabulary", configure: func(s *detector.SecretScanner) { s.SetSuppressionVocabulary(vocabulary) }, newHits: 0},
+        {name: "scoring profile", configure: func(s *detector.SecretScanner) { s.SetProfiles(profiles) }, newHits: 0},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            scanner := detector.NewSecretScanner()
+            tt.configure(scanner)
+            ts := NewTeamsService(&config.Config{TestcasesDir: dir}, scanner, nil, nil)
+
+            report, err := ts.DryRunRule(dryRunRule)
+            if err != nil {
+                t.Fatalf("unexpected error: %v", err)
+            }
+            if len(report.NewHits) != tt.newHits {
+                t.Errorf("got %d new hits, want %d: %+v", len(report.NewHits), tt.newHits, report.NewHits)
+            }
+        })
+    }
+}
+
+func TestDryRunRuleMissingCorpus(t *testing.T) {
+    ts := NewTeamsService(&config.Config{TestcasesDir: filepath.Join(t.TempDir(), "missing")}, detector.NewSecretScanner(), nil, nil)
+
+    _, err := ts.DryRunRule(dryRunRule)
+    if !errors.Is(err, ErrCorpusUnavailable) {
+        t.Fatalf("error = %v, want ErrCorpusUnavailable", err)
+    }
+}
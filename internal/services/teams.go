@@ -2,9 +2,14 @@ package services
 
 import (
 	"log"
+	"sort"
+	"sync"
+	"time"
 
+	"stackguard-task/internal/attachments"
 	"stackguard-task/internal/config"
 	"stackguard-task/internal/detector"
+	"stackguard-task/internal/feedback"
 	"stackguard-task/internal/models"
 	"stackguard-task/internal/storage"
 )
@@ -14,20 +19,60 @@ type TeamsService struct {
     scanner      *detector.SecretScanner
     store        storage.Store
     alertService *AlertService
+
+    // Where attachment content comes from and how much work one may cost
+    fetcher          attachments.Fetcher
+    attachmentLimits attachments.Limits
+
+    // Tails of each user's latest messages per channel, for secrets split across messages
+    recentMutex sync.Mutex
+    recentTails map[string][]detector.MessageTail
+    lastSweep   time.Time // when recentTails was last cleared of expired keys
+
+    // Bodies of recently processed messages by ID, so replies can be scored
+    // with their parent's text when the webhook does not include it
+    parentMutex  sync.Mutex
+    parentBodies map[string]models.MessageBody
+    parentOrder  []string
 }
 
-func NewTeamsService(cfg *config.Config, store storage.Store, alertService *AlertService) *TeamsService {
+// Number of message bodies kept for thread context
+const maxParentBodies = 1000
+
+func NewTeamsService(cfg *config.Config, scanner *detector.SecretScanner, store storage.Store, alertService *AlertService) *TeamsService {
+    limits := attachments.DefaultLimits()
+    if cfg.AttachmentMaxBytes > 0 {
+        limits.MaxBytes = cfg.AttachmentMaxBytes
+    }
+
     return &TeamsService{
-        config:       cfg,
-        scanner:      detector.NewSecretScanner(),
-        store:        store,
-        alertService: alertService,
+        config:           cfg,
+        scanner:          scanner,
+        store:            store,
+        alertService:     alertService,
+        fetcher:          attachments.NewFetcher(cfg.AttachmentDir, cfg.AttachmentHTTPFetch),
+        attachmentLimits: limits,
+        recentTails:      make(map[string][]detector.MessageTail),
+        parentBodies:     make(map[string]models.MessageBody),
     }
 }
 
 func (ts *TeamsService) ProcessMessage(message models.TeamsMessage) ([]models.SecretDetection, error) {
-    // Scan message for secrets (returns deduplicated, sorted by confidence)
-    detections := ts.scanner.ScanMessage(message)
+    ts.resolveThread(&message)
+
+    // Scan message for secrets, also glued to the sender's previous messages
+    // (returns deduplicated, sorted by confidence)
+    tail := ts.scanner.Tail(message)
+    key := message.ChannelID + "/" + message.From.User.ID
+    detections := ts.scanner.ScanMessageWithRecent(message, ts.recentMessages(key, tail.At))
+    ts.rememberMessage(key, tail, detections)
+
+    if ts.config.AttachmentScan && len(message.Attachments) > 0 {
+        detections = append(detections, ts.scanAttachments(message)...)
+        sort.SliceStable(detections, func(i, j int) bool {
+            return detections[i].Confidence > detections[j].Confidence
+        })
+    }
     
     // Save only the highest confidence detection to storage to avoid duplicates
     // but return all detections for API responses
@@ -40,8 +85,12 @@ func (ts *TeamsService) ProcessMessage(message models.TeamsMessage) ([]models.Se
                 highestConfidenceDetection.SecretType, highestConfidenceDetection.ChannelID, 
                 highestConfidenceDetection.UserName, highestConfidenceDetection.Confidence)
             
-            // Send alert via WebSocket
-            if ts.alertService != nil {
+            // Send alert via WebSocket, unless the scoring profile of the
+            // rule and channel asks for more confidence before alerting
+            profile := ts.scanner.Profile(highestConfidenceDetection.RuleID, highestConfidenceDetection.ChannelID, highestConfidenceDetection.TeamID)
+            if highestConfidenceDetection.Confidence < profile.AlertThreshold {
+                log.Printf("Not alerting: confidence below the %s profile's alert threshold %.2f", profile.Name, profile.AlertThreshold)
+            } else if ts.alertService != nil {
                 if err := ts.alertService.SendAlert(highestConfidenceDetection); err != nil {
                     log.Printf("Error sending alert: %v", err)
                 }
@@ -52,14 +101,129 @@ func (ts *TeamsService) ProcessMessage(message models.TeamsMessage) ([]models.Se
     return detections, nil
 }
 
+// scanAttachments scans the text of every attachment of message. An attachment
+// that cannot be fetched or unpacked is logged and skipped.
+func (ts *TeamsService) scanAttachments(message models.TeamsMessage) []models.SecretDetection {
+    var detections []models.SecretDetection
+    for _, attachment := range message.Attachments {
+        files, err := attachments.Read(ts.fetcher, attachment, ts.attachmentLimits)
+        if err != nil {
+            log.Printf("Error reading attachment %s of message %s: %v", attachment.Name, message.ID, err)
+        }
+        for _, file := range files {
+            detections = append(detections, ts.scanner.ScanAttachmentText(message, attachment.Name, file.Entry, file.Text)...)
+        }
+    }
+    return detections
+}
+
+// resolveThread fills in the thread root and, for replies, the parent body
+// from earlier messages. Teams threads are one level deep, so the message
+// replied to is the root.
+func (ts *TeamsService) resolveThread(message *models.TeamsMessage) {
+    if message.ThreadID == "" {
+        message.ThreadID = message.ReplyToID
+    }
+    if message.ThreadID == "" {
+        message.ThreadID = message.ID
+    }
+
+    ts.parentMutex.Lock()
+    defer ts.parentMutex.Unlock()
+
+    if message.ParentBody == nil && message.ReplyToID != "" {
+        if body, ok := ts.parentBodies[message.ReplyToID]; ok {
+            message.ParentBody = &body
+        }
+    }
+
+    if _, ok := ts.parentBodies[message.ID]; !ok {
+        ts.parentOrder = append(ts.parentOrder, message.ID)
+    }
+    ts.parentBodies[message.ID] = message.Body
+    if len(ts.parentOrder) > maxParentBodies {
+        delete(ts.parentBodies, ts.parentOrder[0])
+        ts.parentOrder = ts.parentOrder[1:]
+    }
+}
+
+// recentMessages returns the unexpired tails kept for a channel and user, oldest first
+func (ts *TeamsService) recentMessages(key string, now time.Time) []detector.MessageTail {
+    ts.recentMutex.Lock()
+    defer ts.recentMutex.Unlock()
+
+    maxAge := time.Duration(ts.config.SplitWindowSeconds) * time.Second
+
+    // Senders who stop posting are never looked up again, so once per window
+    // every key whose newest tail has expired is dropped
+    if now.Sub(ts.lastSweep) > maxAge {
+        for other, tails := range ts.recentTails {
+            if len(tails) == 0 || now.Sub(tails[len(tails)-1].At) > maxAge {
+                delete(ts.recentTails, other)
+            }
+        }
+        ts.lastSweep = now
+    }
+
+    var recent []detector.MessageTail
+    for _, tail := range ts.recentTails[key] {
+        if now.Sub(tail.At) <= maxAge {
+            recent = append(recent, tail)
+        }
+    }
+    if len(recent) == 0 {
+        delete(ts.recentTails, key)
+    } else {
+        ts.recentTails[key] = recent
+    }
+    return recent
+}
+
+// rememberMessage adds a tail to the window, dropping the oldest ones. Once a
+// split secret is reported its parts are forgotten so it is not reported again.
+// A message ending in a secret it reported itself ends the chain the same way.
+func (ts *TeamsService) rememberMessage(key string, tail detector.MessageTail, detections []models.SecretDetection) {
+    ts.recentMutex.Lock()
+    defer ts.recentMutex.Unlock()
+
+    limit := ts.config.SplitWindowMessages
+    if limit <= 0 {
+        return
+    }
+    for _, detection := range detections {
+        if len(detection.MessageIDs) > 0 {
+            delete(ts.recentTails, key)
+            return
+        }
+    }
+    if tail.Spent(detections) {
+        delete(ts.recentTails, key)
+        return
+    }
+
+    recent := append(ts.recentTails[key], tail)
+    if len(recent) > limit {
+        recent = recent[len(recent)-limit:]
+    }
+    ts.recentTails[key] = recent
+}
+
 func (ts *TeamsService) GetDetections(limit int) ([]models.SecretDetection, error) {
     return ts.store.GetDetections(limit)
 }
 
-func (ts *TeamsService) GetDetectionsByChannel(channelID string) ([]models.SecretDetection, error) {
+// GetDetectionsByChannel returns a channel's detections, limited to one thread when threadID is set
+func (ts *TeamsService) GetDetectionsByChannel(channelID, threadID string) ([]models.SecretDetection, error) {
+    if threadID != "" {
+        return ts.store.GetDetectionsByThread(channelID, threadID)
+    }
     return ts.store.GetDetectionsByChannel(channelID)
 }
 
+func (ts *TeamsService) GetDetection(id string) (*models.SecretDetection, error) {
+    return ts.store.GetDetectionByID(id)
+}
+
 func (ts *TeamsService) GetStats() (models.DashboardStats, error) {
     return ts.store.GetStats()
 }
@@ -72,6 +236,60 @@ func (ts *TeamsService) ClearAllDetections() error {
     return ts.store.ClearAllDetections()
 }
 
+// ReloadRules reloads the detection rule pack, keeping the previous rules on failure
+func (ts *TeamsService) ReloadRules() error {
+    return ts.scanner.ReloadRules()
+}
+
+func (ts *TeamsService) GetRulesStatus() detector.RulesStatus {
+    return ts.scanner.RulesStatus()
+}
+
 func (ts *TeamsService) GetDetectionsByStatus(status string) ([]models.SecretDetection, error) {
     return ts.store.GetDetectionsByStatus(status)
+}
+
+// GetSuppressions returns the most recent matches the scanner discarded, newest first
+func (ts *TeamsService) GetSuppressions(limit int) []models.SuppressedCandidate {
+    return ts.scanner.Suppressions(limit)
+}
+
+// FeedbackSamples returns every detection an analyst has resolved or marked
+// as a false positive, as training samples
+func (ts *TeamsService) FeedbackSamples() ([]feedback.Sample, error) {
+    detections, err := ts.store.GetDetections(0)
+    if err != nil {
+        return nil, err
+    }
+    return feedback.SamplesFromDetections(detections), nil
+}
+
+// FeedbackModel returns the calibration in use, nil while scoring uses the defaults
+func (ts *TeamsService) FeedbackModel() *detector.Calibration {
+    return ts.scanner.Calibration()
+}
+
+// Retrain learns a new calibration from analyst feedback, switches scanning to
+// it and saves it to FEEDBACK_MODEL when set. The report compares scores on
+// the labelled detections under the previous and new calibration.
+func (ts *TeamsService) Retrain() (feedback.Report, error) {
+    samples, err := ts.FeedbackSamples()
+    if err != nil {
+        return feedback.Report{}, err
+    }
+    calibration, err := feedback.Train(samples)
+    if err != nil {
+        return feedback.Report{}, err
+    }
+
+    report := feedback.Compare(samples, ts.scanner.Calibration(), calibration)
+    ts.scanner.SetCalibration(calibration)
+    log.Printf("Retrained scoring on %d labelled detections (accuracy %.2f -> %.2f)", len(samples), report.Before.Accuracy, report.After.Accuracy)
+
+    if ts.config.FeedbackModel != "" {
+        if err := detector.SaveCalibration(ts.config.FeedbackModel, calibration); err != nil {
+            return report, err
+        }
+    }
+    return report, nil
 }
\ No newline at end of file
@@ -12,6 +12,7 @@ type Store interface {
     SaveDetection(detection models.SecretDetection) error
     GetDetections(limit int) ([]models.SecretDetection, error)
     GetDetectionsByChannel(channelID string) ([]models.SecretDetection, error)
+    GetDetectionsByThread(channelID, threadID string) ([]models.SecretDetection, error)
     GetDetectionsByType(secretType string) ([]models.SecretDetection, error)
     GetStats() (models.DashboardStats, error)
     UpdateDetectionStatus(id, status string) error
@@ -74,6 +75,20 @@ func (ms *MemoryStore) GetDetectionsByChannel(channelID string) ([]models.Secret
     return detections, nil
 }
 
+func (ms *MemoryStore) GetDetectionsByThread(channelID, threadID string) ([]models.SecretDetection, error) {
+    ms.mutex.RLock()
+    defer ms.mutex.RUnlock()
+    
+    var detections []models.SecretDetection
+    for _, detection := range ms.detections {
+        if detection.ChannelID == channelID && detection.ThreadID == threadID {
+            detections = append(detections, detection)
+        }
+    }
+    
+    return detections, nil
+}
+
 func (ms *MemoryStore) GetDetectionsByType(secretType string) ([]models.SecretDetection, error) {
     ms.mutex.RLock()
     defer ms.mutex.RUnlock()